@@ -2,70 +2,378 @@ package pgutil
 
 import (
 	"context"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type BatchInserter struct {
+	*batchWriter
+}
+
+const maxNumPostgresParameters = 65535
+
+func NewBatchInserter(db DB, tableName string, columnNames []string, configs ...BatchInserterConfigFunc) *BatchInserter {
+	options := getBatchInserterOptions(configs)
+
+	newQueryBuilder := func(ctx context.Context) (*batchQueryBuilder, error) {
+		columnTypes := make([]string, len(columnNames))
+		if options.inferColumnTypes {
+			inferredColumnTypes, err := describeBatchColumnTypes(ctx, db, tableName, columnNames)
+			if err != nil {
+				return nil, err
+			}
+
+			columnTypes = inferredColumnTypes
+		}
+
+		for i, name := range columnNames {
+			if columnType, ok := options.columnTypes[name]; ok {
+				columnTypes[i] = columnType
+			}
+		}
+
+		return newBatchQueryBuilder(tableName, columnNames, columnTypes, options.onConflictClause, options.returningClause), nil
+	}
+
+	writer := newBatchWriter(db, len(columnNames), newQueryBuilder, options.returningScanner)
+	writer.setConcurrency(options.concurrency)
+	if len(options.conflictColumns) > 0 {
+		writer.validate = newDuplicateConflictKeyValidator(columnNames, options.conflictColumns)
+	}
+
+	return &BatchInserter{
+		batchWriter: writer,
+	}
+}
+
+func (i *BatchInserter) Insert(ctx context.Context, values ...any) error {
+	return i.write(ctx, values)
+}
+
+//
+//
+
+// batchWriter buffers rows of values and flushes them in batches that stay within
+// the maximum number of parameters Postgres accepts in a single query.
+type batchWriter struct {
 	db               DB
 	numColumns       int
 	maxBatchSize     int
 	maxCapacity      int
+	newQueryBuilder  batchQueryBuilderFactory
 	queryBuilder     *batchQueryBuilder
+	validate         func(batch []any) error
 	returningScanner ScanFunc
 	values           []any
+	metrics          batchMetrics
+
+	// Set only when flushing concurrently
+	sem    chan struct{}
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	errs   []error
+	scanMu sync.Mutex
 }
 
-const maxNumPostgresParameters = 65535
+type batchQueryBuilderFactory func(ctx context.Context) (*batchQueryBuilder, error)
 
-func NewBatchInserter(db DB, tableName string, columnNames []string, configs ...BatchInserterConfigFunc) *BatchInserter {
+func newBatchWriter(db DB, numColumns int, newQueryBuilder batchQueryBuilderFactory, returningScanner ScanFunc) *batchWriter {
 	var (
-		options          = getBatchInserterOptions(configs)
-		numColumns       = len(columnNames)
-		maxBatchSize     = int(maxNumPostgresParameters/numColumns) * numColumns
-		maxCapacity      = maxBatchSize + numColumns
-		queryBuilder     = newBatchQueryBuilder(tableName, columnNames, options.onConflictClause, options.returningClause)
-		returningScanner = options.returningScanner
+		maxBatchSize = int(maxNumPostgresParameters/numColumns) * numColumns
+		maxCapacity  = maxBatchSize + numColumns
 	)
 
-	return &BatchInserter{
+	return &batchWriter{
 		db:               db,
 		numColumns:       numColumns,
 		maxBatchSize:     maxBatchSize,
 		maxCapacity:      maxCapacity,
-		queryBuilder:     queryBuilder,
+		newQueryBuilder:  newQueryBuilder,
 		returningScanner: returningScanner,
 		values:           make([]any, 0, maxCapacity),
 	}
 }
 
-func (i *BatchInserter) Insert(ctx context.Context, values ...any) error {
-	if len(values) != i.numColumns {
-		return fmt.Errorf("received %d values for %d columns", len(values), i.numColumns)
+// setConcurrency allows up to n batches to be in flight at once. Concurrent flushing
+// is not possible within a transaction, in which case batches are flushed serially.
+func (w *batchWriter) setConcurrency(n int) {
+	if n > 1 && !w.db.IsInTransaction() {
+		w.sem = make(chan struct{}, n)
+	}
+}
+
+func (w *batchWriter) write(ctx context.Context, values []any) error {
+	if len(values) != w.numColumns {
+		return fmt.Errorf("received %d values for %d columns", len(values), w.numColumns)
 	}
 
-	i.values = append(i.values, values...)
+	w.values = append(w.values, values...)
 
-	if len(i.values) >= i.maxBatchSize {
-		return i.Flush(ctx)
+	if len(w.values) >= w.maxBatchSize {
+		return w.flushBatch(ctx)
 	}
 
 	return nil
 }
 
-func (i *BatchInserter) Flush(ctx context.Context) error {
-	if len(i.values) == 0 {
-		return nil
+// Flush writes all buffered values and waits for all in-flight batches to complete.
+// When flushing concurrently, errors from all batches sent since the previous call
+// to Flush are returned together.
+func (w *batchWriter) Flush(ctx context.Context) error {
+	var err error
+	for len(w.values) > 0 && err == nil {
+		err = w.flushBatch(ctx)
 	}
 
-	n := i.maxBatchSize
-	if len(i.values) < i.maxBatchSize {
-		n = len(i.values)
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err = errors.Join(append(w.errs, err)...)
+	w.errs = nil
+
+	return err
+}
+
+func (w *batchWriter) flushBatch(ctx context.Context) error {
+	if w.queryBuilder == nil {
+		queryBuilder, err := w.newQueryBuilder(ctx)
+		if err != nil {
+			return err
+		}
+
+		w.queryBuilder = queryBuilder
 	}
 
-	batch := i.values[:n]
-	i.values = append(make([]any, 0, i.maxCapacity), i.values[n:]...)
+	n := w.maxBatchSize
+	if len(w.values) < w.maxBatchSize {
+		n = len(w.values)
+	}
+
+	batch := w.values[:n]
+	w.values = append(make([]any, 0, w.maxCapacity), w.values[n:]...)
+
+	if w.validate != nil {
+		// A rejected batch is dropped so that later writes are not blocked by it
+		if err := w.validate(batch); err != nil {
+			return err
+		}
+	}
 
 	batchSize := len(batch)
-	query := i.queryBuilder.build(batchSize)
-	return NewRowScanner(i.returningScanner)(i.db.Query(ctx, RawQuery(query, batch...)))
+	query := w.queryBuilder.build(batchSize)
+	q := RawQuery(query, batch...)
+	if batchSize == w.maxBatchSize {
+		// Full-size batches all share the same query text
+		q = q.Prepared()
+	}
+
+	var (
+		numRows = batchSize / w.numColumns
+		bytes   = approximateBatchBytes(query, batch)
+	)
+
+	if w.sem == nil {
+		return w.send(ctx, q, numRows, bytes)
+	}
+
+	// Block until there's room for another in-flight batch
+	select {
+	case w.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer func() {
+			<-w.sem
+			w.wg.Done()
+		}()
+
+		if err := w.send(ctx, q, numRows, bytes); err != nil {
+			w.mu.Lock()
+			w.errs = append(w.errs, err)
+			w.mu.Unlock()
+		}
+	}()
+
+	return nil
+}
+
+func (w *batchWriter) send(ctx context.Context, q Q, numRows, bytes int) (err error) {
+	start := time.Now()
+	defer func() { w.metrics.record(err, numRows, bytes, time.Since(start)) }()
+
+	rows, err := w.db.Query(ctx, q)
+
+	if w.sem != nil {
+		// Do not invoke the returning scanner from multiple goroutines at once
+		w.scanMu.Lock()
+		defer w.scanMu.Unlock()
+	}
+
+	return NewRowScanner(w.returningScanner)(rows, err)
+}
+
+// Metrics returns a snapshot of the work performed so far. It is safe to call
+// concurrently with Flush.
+func (w *batchWriter) Metrics() BatchMetrics {
+	return w.metrics.snapshot()
+}
+
+// BatchMetrics summarizes the batches sent by a batch writer. Rows counts the rows sent
+// rather than the rows affected, which differ when conflicting rows are skipped (e.g.,
+// by ON CONFLICT DO NOTHING or the WHERE clause of an upsert).
+type BatchMetrics struct {
+	Rows          int64         // rows sent by successful batches
+	Flushes       int64         // batches sent to the database
+	FailedFlushes int64         // batches that returned an error
+	Bytes         int64         // approximate size of query text and arguments sent
+	Duration      time.Duration // total time spent sending batches and scanning results
+}
+
+type batchMetrics struct {
+	rows          atomic.Int64
+	flushes       atomic.Int64
+	failedFlushes atomic.Int64
+	bytes         atomic.Int64
+	duration      atomic.Int64
+}
+
+func (m *batchMetrics) record(err error, rows, bytes int, duration time.Duration) {
+	m.flushes.Add(1)
+	m.bytes.Add(int64(bytes))
+	m.duration.Add(int64(duration))
+
+	if err != nil {
+		m.failedFlushes.Add(1)
+	} else {
+		m.rows.Add(int64(rows))
+	}
+}
+
+func (m *batchMetrics) snapshot() BatchMetrics {
+	return BatchMetrics{
+		Rows:          m.rows.Load(),
+		Flushes:       m.flushes.Load(),
+		FailedFlushes: m.failedFlushes.Load(),
+		Bytes:         m.bytes.Load(),
+		Duration:      time.Duration(m.duration.Load()),
+	}
+}
+
+func approximateBatchBytes(query string, args []any) int {
+	size := len(query)
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			size += len(v)
+		case []byte:
+			size += len(v)
+		default:
+			size += 8
+		}
+	}
+
+	return size
+}
+
+// BatchDuplicateConflictKeyError is returned when a batch of upserted rows contains
+// multiple rows with the same conflict key. The rows of the rejected batch are not
+// written and are no longer buffered.
+type BatchDuplicateConflictKeyError struct {
+	Columns []string
+	Values  []any
+}
+
+func (e *BatchDuplicateConflictKeyError) Error() string {
+	return fmt.Sprintf("batch contains multiple rows with conflict key (%s) = %v", strings.Join(e.Columns, ", "), e.Values)
+}
+
+func newDuplicateConflictKeyValidator(columnNames, conflictColumns []string) func(batch []any) error {
+	indexes := make([]int, 0, len(conflictColumns))
+	for _, conflictColumn := range conflictColumns {
+		index := slices.Index(columnNames, conflictColumn)
+		if index < 0 {
+			return func(batch []any) error {
+				return fmt.Errorf("conflict column %q is not an inserted column", conflictColumn)
+			}
+		}
+
+		indexes = append(indexes, index)
+	}
+
+	numColumns := len(columnNames)
+
+	return func(batch []any) error {
+		seen := make(map[string]struct{}, len(batch)/numColumns)
+		for offset := 0; offset < len(batch); offset += numColumns {
+			values := make([]any, 0, len(indexes))
+			for _, index := range indexes {
+				values = append(values, batch[offset+index])
+			}
+
+			values = normalizeConflictKey(values)
+			if slices.Contains(values, nil) {
+				// NULLs are distinct from one another, so such rows never conflict
+				continue
+			}
+
+			key := conflictKeyString(values)
+			if _, ok := seen[key]; ok {
+				return &BatchDuplicateConflictKeyError{
+					Columns: conflictColumns,
+					Values:  values,
+				}
+			}
+			seen[key] = struct{}{}
+		}
+
+		return nil
+	}
+}
+
+func normalizeConflictKey(values []any) []any {
+	normalized := make([]any, 0, len(values))
+	for _, value := range values {
+		// Compare values as they would be sent to the database (e.g., dereferenced)
+		if valuer, ok := value.(driver.Valuer); ok {
+			if v, err := valuer.Value(); err == nil {
+				value = v
+			}
+		}
+		if v, err := driver.DefaultParameterConverter.ConvertValue(value); err == nil {
+			value = v
+		}
+
+		normalized = append(normalized, value)
+	}
+
+	return normalized
+}
+
+// conflictKeyString returns a comparable representation of normalized conflict key
+// values. Values that Postgres would consider equal (e.g., a string and its bytes,
+// or the same instant in different locations) share the same representation.
+func conflictKeyString(values []any) string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		switch v := value.(type) {
+		case []byte:
+			value = string(v)
+		case time.Time:
+			value = v.UTC().Format(time.RFC3339Nano)
+		}
+
+		parts = append(parts, fmt.Sprintf("%#v", value))
+	}
+
+	return strings.Join(parts, ",")
 }
@@ -0,0 +1,48 @@
+package pgutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type BatchDeleter struct {
+	*batchWriter
+}
+
+// NewBatchDeleter creates a batch writer that deletes rows matching the given key
+// columns. Values are supplied to Delete in the order of the key columns. The types
+// of the key columns are read from the database on first flush so that the
+// placeholders of the VALUES list can be cast explicitly.
+func NewBatchDeleter(db DB, tableName string, keyColumnNames []string, configs ...BatchDeleterConfigFunc) *BatchDeleter {
+	options := getBatchDeleterOptions(configs)
+
+	newQueryBuilder := func(ctx context.Context) (*batchQueryBuilder, error) {
+		columnTypes, err := describeBatchColumnTypes(ctx, db, tableName, keyColumnNames)
+		if err != nil {
+			return nil, err
+		}
+
+		return newBatchDeleteQueryBuilder(tableName, keyColumnNames, columnTypes, options.returningClause), nil
+	}
+
+	return &BatchDeleter{
+		batchWriter: newBatchWriter(db, len(keyColumnNames), newQueryBuilder, options.returningScanner),
+	}
+}
+
+func (d *BatchDeleter) Delete(ctx context.Context, values ...any) error {
+	return d.write(ctx, values)
+}
+
+func newBatchDeleteQueryBuilder(tableName string, keyColumnNames, columnTypes []string, returningClause string) *batchQueryBuilder {
+	var (
+		// NOTE: A list of row constructors (`IN ((...), (...))`) is expanded into one
+		// condition per row, which plans poorly for large batches. The placeholders of
+		// the VALUES list are cast explicitly, as they would otherwise resolve as text.
+		queryPrefix = fmt.Sprintf("DELETE FROM %q WHERE (%s) IN (VALUES", tableName, strings.Join(quoteColumnNames(keyColumnNames), ", "))
+		querySuffix = fmt.Sprintf(") %s", returningClause)
+	)
+
+	return newBatchQueryBuilderFromParts(queryPrefix, querySuffix, len(keyColumnNames), columnTypes)
+}
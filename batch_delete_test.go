@@ -0,0 +1,70 @@
+package pgutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchDeleter(t *testing.T) {
+	var (
+		db          = NewTestDB(t)
+		ctx         = context.Background()
+		numRows     = 100000
+		numPayloads = 100
+		columns     = []string{"w", "x", "y", "z", "q", "payload"}
+	)
+
+	setupTestBatchTable(t, db)
+	payloads := createBatchPayloads(t, numPayloads)
+	rowValues := createBatchRowValues(t, numRows, payloads)
+	runBatchInserter(t, NewBatchInserter(db, "test", columns), rowValues)
+
+	// Delete every other row keyed on columns "w" and "x"
+	expectedValues := make([]any, 0, numRows/2)
+	deleter := NewBatchDeleter(db, "test", []string{"w", "x"})
+	for i, values := range rowValues {
+		if i%2 == 0 {
+			require.NoError(t, deleter.Delete(ctx, values[0], values[1]))
+		} else {
+			expectedValues = append(expectedValues, values[0])
+		}
+	}
+	require.NoError(t, deleter.Flush(ctx))
+
+	assertBatchInsertedValues(t, db, "w", expectedValues)
+}
+
+func TestBatchDeleterWithReturning(t *testing.T) {
+	var (
+		db        = NewTestDB(t)
+		ctx       = context.Background()
+		columns   = []string{"w", "x", "y", "z", "q", "payload"}
+		collector = NewCollector(NewAnyValueScanner[int]())
+	)
+
+	setupTestBatchTable(t, db)
+	payloads := createBatchPayloads(t, 10)
+	rowValues := createBatchRowValues(t, 100, payloads)
+	runBatchInserter(t, NewBatchInserter(db, "test", columns), rowValues)
+
+	// Delete rows keyed on column "w" and assert scanned serial ids
+	deleter := NewBatchDeleter(db, "test", []string{"w"}, WithBatchDeleterReturn([]string{"id"}, collector.Scanner()))
+	require.NoError(t, deleter.Delete(ctx, rowValues[3][0]))
+	require.NoError(t, deleter.Delete(ctx, rowValues[5][0]))
+	require.NoError(t, deleter.Delete(ctx, int64(-1))) // no match
+	require.NoError(t, deleter.Flush(ctx))
+
+	assert.ElementsMatch(t, []int{4, 6}, collector.Slice())
+}
+
+func TestBatchDeleteQueryBuilder(t *testing.T) {
+	builder := newBatchDeleteQueryBuilder("test", []string{"w", "x"}, []string{"integer", "text"}, `RETURNING "id"`)
+
+	assert.Equal(t,
+		`DELETE FROM "test" WHERE ("w", "x") IN (VALUES ($00001::integer,$00002::text),($00003::integer,$00004::text) ) RETURNING "id"`,
+		builder.build(4),
+	)
+}
@@ -7,9 +7,13 @@ import (
 
 type (
 	batchInserterOptions struct {
+		columnTypes      map[string]string
+		inferColumnTypes bool
 		onConflictClause string
+		conflictColumns  []string
 		returningClause  string
 		returningScanner ScanFunc
+		concurrency      int
 	}
 
 	BatchInserterConfigFunc func(*batchInserterOptions)
@@ -24,15 +28,137 @@ func getBatchInserterOptions(configs []BatchInserterConfigFunc) *batchInserterOp
 	return options
 }
 
+// WithBatchInserterColumnTypes casts the placeholders of the given columns to the
+// given types (e.g., `uuid`, `jsonb`, `my_enum`, `text[]`).
+func WithBatchInserterColumnTypes(columnTypes map[string]string) BatchInserterConfigFunc {
+	return func(o *batchInserterOptions) {
+		if o.columnTypes == nil {
+			o.columnTypes = map[string]string{}
+		}
+
+		for name, columnType := range columnTypes {
+			o.columnTypes[name] = columnType
+		}
+	}
+}
+
+// WithBatchInserterInferredColumnTypes casts the placeholders of every column to the
+// column's type as read from the database on first flush.
+// Types supplied via WithBatchInserterColumnTypes take precedence.
+func WithBatchInserterInferredColumnTypes() BatchInserterConfigFunc {
+	return func(o *batchInserterOptions) {
+		o.inferColumnTypes = true
+	}
+}
+
 func WithBatchInserterOnConflict(clause string) BatchInserterConfigFunc {
 	return func(o *batchInserterOptions) {
 		o.onConflictClause = fmt.Sprintf("ON CONFLICT %s", clause)
+		o.conflictColumns = nil
+	}
+}
+
+// WithBatchInserterUpsert generates an `ON CONFLICT (...) DO UPDATE` clause that
+// overwrites the given update columns with the values of the conflicting insert. If
+// a where clause is supplied, only conflicting rows matching it are updated. If no
+// update columns are supplied, conflicting rows are ignored.
+//
+// Postgres rejects a single statement that updates the same row twice, so each flush
+// is checked for duplicate conflict keys before it is sent. These are reported with a
+// *BatchDuplicateConflictKeyError, and the rows of the rejected flush are discarded.
+func WithBatchInserterUpsert(conflictColumns, updateColumns []string, whereClause string) BatchInserterConfigFunc {
+	return func(o *batchInserterOptions) {
+		o.onConflictClause = makeUpsertClause(conflictColumns, updateColumns, whereClause)
+		o.conflictColumns = nil
+
+		if len(updateColumns) > 0 {
+			o.conflictColumns = conflictColumns
+		}
 	}
 }
 
+func makeUpsertClause(conflictColumns, updateColumns []string, whereClause string) string {
+	target := strings.Join(quoteColumnNames(conflictColumns), ", ")
+	if len(updateColumns) == 0 {
+		return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", target)
+	}
+
+	assignments := make([]string, 0, len(updateColumns))
+	for _, name := range updateColumns {
+		assignments = append(assignments, fmt.Sprintf("%s = EXCLUDED.%s", quoteColumnName(name), quoteColumnName(name)))
+	}
+
+	clause := fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", target, strings.Join(assignments, ", "))
+	if whereClause != "" {
+		clause += fmt.Sprintf(" WHERE %s", whereClause)
+	}
+
+	return clause
+}
+
 func WithBatchInserterReturn(columns []string, scanner ScanFunc) BatchInserterConfigFunc {
 	return func(o *batchInserterOptions) {
 		o.returningClause = fmt.Sprintf("RETURNING %s", strings.Join(quoteColumnNames(columns), ", "))
 		o.returningScanner = scanner
 	}
 }
+
+// WithBatchInserterConcurrency sends up to n batches to the database concurrently.
+// Insert blocks once n batches are in flight. Errors from concurrent batches are
+// reported by Flush, which waits for all in-flight batches. Returning scanners are
+// never invoked concurrently. This option has no effect within a transaction.
+func WithBatchInserterConcurrency(n int) BatchInserterConfigFunc {
+	return func(o *batchInserterOptions) {
+		o.concurrency = n
+	}
+}
+
+type (
+	batchUpdaterOptions struct {
+		returningColumns []string
+		returningScanner ScanFunc
+	}
+
+	BatchUpdaterConfigFunc func(*batchUpdaterOptions)
+)
+
+func getBatchUpdaterOptions(configs []BatchUpdaterConfigFunc) *batchUpdaterOptions {
+	options := &batchUpdaterOptions{}
+	for _, f := range configs {
+		f(options)
+	}
+
+	return options
+}
+
+func WithBatchUpdaterReturn(columns []string, scanner ScanFunc) BatchUpdaterConfigFunc {
+	return func(o *batchUpdaterOptions) {
+		o.returningColumns = columns
+		o.returningScanner = scanner
+	}
+}
+
+type (
+	batchDeleterOptions struct {
+		returningClause  string
+		returningScanner ScanFunc
+	}
+
+	BatchDeleterConfigFunc func(*batchDeleterOptions)
+)
+
+func getBatchDeleterOptions(configs []BatchDeleterConfigFunc) *batchDeleterOptions {
+	options := &batchDeleterOptions{}
+	for _, f := range configs {
+		f(options)
+	}
+
+	return options
+}
+
+func WithBatchDeleterReturn(columns []string, scanner ScanFunc) BatchDeleterConfigFunc {
+	return func(o *batchDeleterOptions) {
+		o.returningClause = fmt.Sprintf("RETURNING %s", strings.Join(quoteColumnNames(columns), ", "))
+		o.returningScanner = scanner
+	}
+}
@@ -22,21 +22,44 @@ func init() {
 
 type batchQueryBuilder struct {
 	numColumns   int
+	rowLen       int
 	queryPrefix  string
 	querySuffix  string
 	placeholders string
 }
 
-func newBatchQueryBuilder(tableName string, columnNames []string, onConflictClause, returningClause string) *batchQueryBuilder {
+func newBatchQueryBuilder(tableName string, columnNames, columnTypes []string, onConflictClause, returningClause string) *batchQueryBuilder {
 	var (
-		numColumns  = len(columnNames)
 		queryPrefix = fmt.Sprintf("INSERT INTO %q (%s) VALUES", tableName, strings.Join(quoteColumnNames(columnNames), ", "))
 		querySuffix = fmt.Sprintf("%s %s", onConflictClause, returningClause)
-		all         = makeBatchPlaceholdersString(numColumns)
 	)
 
+	return newBatchQueryBuilderFromParts(queryPrefix, querySuffix, len(columnNames), columnTypes)
+}
+
+// newBatchQueryBuilderFromParts creates a builder that renders `<prefix> <rows> <suffix>`,
+// where rows is a comma-separated list of parenthesized placeholder tuples. If column
+// types are supplied, each placeholder with a non-empty type is rendered with an
+// explicit cast.
+func newBatchQueryBuilderFromParts(queryPrefix, querySuffix string, numColumns int, columnTypes []string) *batchQueryBuilder {
+	var (
+		all    string
+		rowLen = sequenceLen(numColumns, placeholderLen) + 2 // e.g., `($00123,$001234,...)`
+	)
+
+	if !hasColumnTypes(columnTypes) {
+		all = makeBatchPlaceholdersString(numColumns)
+	} else {
+		all = makeCastBatchPlaceholdersString(columnTypes)
+
+		for _, columnType := range columnTypes {
+			rowLen += len(castSuffix(columnType))
+		}
+	}
+
 	return &batchQueryBuilder{
 		numColumns:   numColumns,
+		rowLen:       rowLen,
 		queryPrefix:  queryPrefix,
 		querySuffix:  querySuffix,
 		placeholders: all,
@@ -44,7 +67,7 @@ func newBatchQueryBuilder(tableName string, columnNames []string, onConflictClau
 }
 
 func (b *batchQueryBuilder) build(batchSize int) string {
-	return fmt.Sprintf("%s %s %s", b.queryPrefix, b.placeholders[:placeholdersLen(b.numColumns, batchSize)], b.querySuffix)
+	return fmt.Sprintf("%s %s %s", b.queryPrefix, b.placeholders[:placeholdersLen(b.rowLen, b.numColumns, batchSize)], b.querySuffix)
 }
 
 func makeBatchPlaceholdersString(numColumns int) string {
@@ -73,12 +96,59 @@ func makeBatchPlaceholdersString(numColumns int) string {
 	return placeholders
 }
 
-func placeholdersLen(numColumns, batchSize int) int {
+func makeCastBatchPlaceholdersString(columnTypes []string) string {
+	var (
+		numColumns = len(columnTypes)
+		casts      = make([]string, 0, numColumns)
+	)
+
+	for _, columnType := range columnTypes {
+		casts = append(casts, castSuffix(columnType))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("(")
+	sb.WriteString(placeholders[0])
+	sb.WriteString(casts[0])
+	for i := 1; i < maxNumPostgresParameters; i++ {
+		if i%numColumns == 0 {
+			sb.WriteString("),(")
+		} else {
+			sb.WriteString(",")
+		}
+
+		sb.WriteString(placeholders[i])
+		sb.WriteString(casts[i%numColumns])
+	}
+	sb.WriteString(")")
+
+	return sb.String()
+}
+
+func hasColumnTypes(columnTypes []string) bool {
+	for _, columnType := range columnTypes {
+		if columnType != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func castSuffix(columnType string) string {
+	if columnType == "" {
+		return ""
+	}
+
+	return "::" + columnType
+}
+
+const placeholderLen = 6 // e.g., `$00123`
+
+func placeholdersLen(rowLen, numColumns, batchSize int) int {
 	var (
-		numRows        = batchSize / numColumns
-		placeholderLen = 6                                           // e.g., `$00123`
-		rowLen         = sequenceLen(numColumns, placeholderLen) + 2 // e.g., `($00123,$001234,...)`
-		totalLen       = sequenceLen(numRows, rowLen)
+		numRows  = batchSize / numColumns
+		totalLen = sequenceLen(numRows, rowLen)
 	)
 
 	return totalLen
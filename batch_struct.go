@@ -0,0 +1,113 @@
+package pgutil
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// StructBatchInserter is a BatchInserter that derives column names and values from
+// the `db` struct tags of T. Fields without a `db` tag (or tagged with `db:"-"`) are
+// not inserted. The fields of an untagged embedded struct are inserted as if they
+// were fields of T, while a tagged embedded field is inserted as a single column.
+type StructBatchInserter[T any] struct {
+	*BatchInserter
+	columnNames  []string
+	fieldIndexes [][]int
+}
+
+func NewStructBatchInserter[T any](db DB, tableName string, configs ...BatchInserterConfigFunc) (*StructBatchInserter[T], error) {
+	columnNames, fieldIndexes, err := structColumns(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	return &StructBatchInserter[T]{
+		BatchInserter: NewBatchInserter(db, tableName, columnNames, configs...),
+		columnNames:   columnNames,
+		fieldIndexes:  fieldIndexes,
+	}, nil
+}
+
+func (i *StructBatchInserter[T]) Insert(ctx context.Context, value T) error {
+	var (
+		rv     = reflect.ValueOf(value)
+		values = make([]any, 0, len(i.fieldIndexes))
+	)
+
+	for _, index := range i.fieldIndexes {
+		field, err := rv.FieldByIndexErr(index)
+		if err != nil {
+			return err
+		}
+
+		values = append(values, field.Interface())
+	}
+
+	return i.BatchInserter.Insert(ctx, values...)
+}
+
+func (i *StructBatchInserter[T]) ColumnNames() []string {
+	return i.columnNames
+}
+
+func structColumns(t reflect.Type) (columnNames []string, fieldIndexes [][]int, _ error) {
+	if t.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("%s is not a struct type", t)
+	}
+
+	var (
+		seen          = map[string]struct{}{}
+		taggedEmbeds  [][]int
+		isTaggedEmbed = func(index []int) bool {
+			for _, prefix := range taggedEmbeds {
+				if len(index) > len(prefix) && slices.Equal(index[:len(prefix)], prefix) {
+					return true
+				}
+			}
+
+			return false
+		}
+	)
+
+	// NOTE: VisibleFields lists each embedded field immediately before its promoted fields
+	for _, field := range reflect.VisibleFields(t) {
+		if isTaggedEmbed(field.Index) {
+			// Promoted from an embedded field inserted as a single column
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup("db")
+		if !ok {
+			// Untagged embedded structs contribute their promoted fields
+			continue
+		}
+		if field.Anonymous {
+			taggedEmbeds = append(taggedEmbeds, field.Index)
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name := strings.TrimSpace(strings.Split(tag, ",")[0])
+		if name == "" || name == "-" {
+			continue
+		}
+
+		if _, ok := seen[name]; ok {
+			return nil, nil, fmt.Errorf("duplicate column %q in %s", name, t)
+		}
+		seen[name] = struct{}{}
+
+		columnNames = append(columnNames, name)
+		fieldIndexes = append(fieldIndexes, field.Index)
+	}
+
+	if len(columnNames) == 0 {
+		return nil, nil, fmt.Errorf("%s has no fields with a db tag", t)
+	}
+
+	return columnNames, fieldIndexes, nil
+}
@@ -0,0 +1,97 @@
+package pgutil
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBatchRowCommon struct {
+	Payload string `db:"payload"`
+}
+
+type testBatchRow struct {
+	testBatchRowCommon
+	W        int64  `db:"w"`
+	X        int64  `db:"x"`
+	Y        int64  `db:"y"`
+	Z        int64  `db:"z"`
+	Q        int64  `db:"q"`
+	Ignored  string `db:"-"`
+	Untagged string
+}
+
+func TestStructBatchInserter(t *testing.T) {
+	var (
+		db          = NewTestDB(t)
+		ctx         = context.Background()
+		numRows     = 100000
+		numPayloads = 100
+	)
+
+	setupTestBatchTable(t, db)
+	payloads := createBatchPayloads(t, numPayloads)
+	rowValues := createBatchRowValues(t, numRows, payloads)
+
+	inserter, err := NewStructBatchInserter[testBatchRow](db, "test")
+	require.NoError(t, err)
+
+	expectedValues := make([]any, 0, numRows)
+	for _, values := range rowValues {
+		require.NoError(t, inserter.Insert(ctx, testBatchRow{
+			testBatchRowCommon: testBatchRowCommon{Payload: values[5].(string)},
+			W:                  values[0].(int64),
+			X:                  values[1].(int64),
+			Y:                  values[2].(int64),
+			Z:                  values[3].(int64),
+			Q:                  values[4].(int64),
+			Ignored:            "ignored",
+		}))
+
+		expectedValues = append(expectedValues, values[0])
+	}
+	require.NoError(t, inserter.Flush(ctx))
+	assertBatchInsertedValues(t, db, "w", expectedValues)
+
+	metrics := inserter.Metrics()
+	assert.Equal(t, int64(numRows), metrics.Rows)
+	assert.Equal(t, int64(10), metrics.Flushes)
+	assert.Equal(t, int64(0), metrics.FailedFlushes)
+	assert.Greater(t, metrics.Bytes, int64(0))
+	assert.Greater(t, metrics.Duration, time.Duration(0))
+}
+
+func TestStructColumns(t *testing.T) {
+	columnNames, fieldIndexes, err := structColumns(reflect.TypeOf(testBatchRow{}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"payload", "w", "x", "y", "z", "q"}, columnNames)
+	assert.Equal(t, [][]int{{0, 0}, {1}, {2}, {3}, {4}, {5}}, fieldIndexes)
+
+	type TaggedEmbed struct {
+		A int `db:"a"`
+	}
+	columnNames, fieldIndexes, err = structColumns(reflect.TypeOf(struct {
+		TaggedEmbed `db:"embed"`
+		time.Time   `db:"created_at"`
+		B           int `db:"b"`
+	}{}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"embed", "created_at", "b"}, columnNames)
+	assert.Equal(t, [][]int{{0}, {1}, {2}}, fieldIndexes)
+
+	_, _, err = structColumns(reflect.TypeOf(42))
+	assert.EqualError(t, err, "int is not a struct type")
+
+	_, _, err = structColumns(reflect.TypeOf(struct{ A int }{}))
+	assert.EqualError(t, err, "struct { A int } has no fields with a db tag")
+
+	_, _, err = structColumns(reflect.TypeOf(struct {
+		A int `db:"a"`
+		B int `db:"a"`
+	}{}))
+	assert.ErrorContains(t, err, `duplicate column "a"`)
+}
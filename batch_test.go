@@ -3,7 +3,9 @@ package pgutil
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -148,7 +150,7 @@ func assertBatchInsertedValues(t testing.TB, db DB, columnName string, expectedV
 	t.Helper()
 	ctx := context.Background()
 
-	values, err := ScanAnys(db.Query(ctx, Query("SELECT {:col} FROM test", Args{"col": Quote(columnName)})))
+	values, err := ScanAnys(db.Query(ctx, Query("SELECT {:col} FROM test ORDER BY w", Args{"col": Quote(columnName)})))
 	require.NoError(t, err)
 	assert.Equal(t, expectedValues, values)
 }
@@ -180,3 +182,271 @@ func createBatchRowValues(t testing.TB, n int, payloads []string) [][]any {
 
 	return values
 }
+
+func TestBatchInserterWithColumnTypes(t *testing.T) {
+	setup := func(t *testing.T) DB {
+		db := NewTestDB(t)
+
+		require.NoError(t, db.Exec(context.Background(), RawQuery(`
+			CREATE TYPE mood AS ENUM ('sad', 'ok', 'happy');
+			CREATE TABLE typed (
+				id      uuid PRIMARY KEY,
+				mood    mood NOT NULL,
+				payload jsonb NOT NULL,
+				tags    text[] NOT NULL
+			);
+		`)))
+
+		return db
+	}
+
+	var (
+		columns   = []string{"id", "mood", "payload", "tags"}
+		rowValues = [][]any{
+			{"6b6a1f4e-3d1c-4a52-9a55-4f8f1c0b2a01", "happy", `{"a": 1}`, "{x,y}"},
+			{"6b6a1f4e-3d1c-4a52-9a55-4f8f1c0b2a02", "sad", `{"b": 2}`, "{z}"},
+		}
+		expectedMoods = []string{"happy", "sad"}
+	)
+
+	t.Run("explicit", func(t *testing.T) {
+		db := setup(t)
+
+		inserter := NewBatchInserter(db, "typed", columns, WithBatchInserterColumnTypes(map[string]string{
+			"id":      "uuid",
+			"mood":    "mood",
+			"payload": "jsonb",
+			"tags":    "text[]",
+		}))
+		runBatchInserter(t, inserter, rowValues)
+
+		moods, err := ScanStrings(db.Query(context.Background(), RawQuery("SELECT mood::text FROM typed ORDER BY id")))
+		require.NoError(t, err)
+		assert.Equal(t, expectedMoods, moods)
+	})
+
+	t.Run("inferred", func(t *testing.T) {
+		db := setup(t)
+
+		inserter := NewBatchInserter(db, "typed", columns, WithBatchInserterInferredColumnTypes())
+		runBatchInserter(t, inserter, rowValues)
+
+		moods, err := ScanStrings(db.Query(context.Background(), RawQuery("SELECT mood::text FROM typed ORDER BY id")))
+		require.NoError(t, err)
+		assert.Equal(t, expectedMoods, moods)
+	})
+
+	t.Run("inferred length-constrained", func(t *testing.T) {
+		db := NewTestDB(t)
+		ctx := context.Background()
+		require.NoError(t, db.Exec(ctx, RawQuery(`CREATE TABLE constrained (id integer PRIMARY KEY, code varchar(3) NOT NULL)`)))
+
+		// Over-length values are rejected rather than truncated by a cast
+		inserter := NewBatchInserter(db, "constrained", []string{"id", "code"}, WithBatchInserterInferredColumnTypes())
+		require.NoError(t, inserter.Insert(ctx, 1, "abcdef"))
+		require.ErrorContains(t, inserter.Flush(ctx), "value too long for type character varying(3)")
+
+		count, _, err := ScanInt(db.Query(ctx, RawQuery(`SELECT COUNT(*) FROM constrained`)))
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+}
+
+func TestBatchQueryBuilderWithColumnTypes(t *testing.T) {
+	builder := newBatchQueryBuilder("test", []string{"id", "name", "payload"}, []string{"uuid", "", "jsonb"}, "", "")
+
+	assert.Equal(t,
+		`INSERT INTO "test" ("id", "name", "payload") VALUES ($00001::uuid,$00002,$00003::jsonb),($00004::uuid,$00005,$00006::jsonb)`,
+		strings.TrimSpace(builder.build(6)),
+	)
+}
+
+func TestBatchInserterWithUpsert(t *testing.T) {
+	t.Run("update", func(t *testing.T) {
+		var (
+			db          = NewTestDB(t)
+			numRows     = 100000
+			numPayloads = 100
+			columns     = []string{"w", "x", "y", "z", "q", "payload"}
+		)
+
+		setupTestBatchTable(t, db)
+		payloads := createBatchPayloads(t, numPayloads)
+		initialRowValues := createBatchRowValues(t, numRows/4, payloads)
+		rowValues := createBatchRowValues(t, numRows, payloads)
+		runBatchInserter(t, NewBatchInserter(db, "test", columns), initialRowValues)
+
+		updatedRowValues := make([][]any, 0, len(rowValues))
+		expectedValues := make([]any, 0, numRows)
+		for i, values := range rowValues {
+			updatedValues := append([]any(nil), values...)
+			updatedValues[1] = int64(-i)
+			updatedRowValues = append(updatedRowValues, updatedValues)
+
+			if i < len(initialRowValues) && i%2 == 0 {
+				// updated (matches where clause)
+				expectedValues = append(expectedValues, int64(-i))
+			} else if i < len(initialRowValues) {
+				// not updated (conflicting, but does not match where clause)
+				expectedValues = append(expectedValues, values[1])
+			} else {
+				// inserted
+				expectedValues = append(expectedValues, int64(-i))
+			}
+		}
+
+		// Upsert rows and assert values of column "x"
+		inserter := NewBatchInserter(db, "test", columns, WithBatchInserterUpsert([]string{"w"}, []string{"x", "payload"}, `test.w % 4 = 1`))
+		runBatchInserter(t, inserter, updatedRowValues)
+		assertBatchInsertedValues(t, db, "x", expectedValues)
+	})
+
+	t.Run("duplicate conflict keys", func(t *testing.T) {
+		var (
+			db      = NewTestDB(t)
+			ctx     = context.Background()
+			columns = []string{"w", "x", "y", "z", "q", "payload"}
+		)
+
+		setupTestBatchTable(t, db)
+		inserter := NewBatchInserter(db, "test", columns, WithBatchInserterUpsert([]string{"w"}, []string{"x"}, ""))
+		require.NoError(t, inserter.Insert(ctx, 1, 2, 3, 4, 5, "a"))
+		require.NoError(t, inserter.Insert(ctx, 6, 7, 8, 9, 10, "b"))
+		require.NoError(t, inserter.Insert(ctx, 1, 12, 13, 14, 15, "c"))
+
+		var duplicateErr *BatchDuplicateConflictKeyError
+		require.ErrorAs(t, inserter.Flush(ctx), &duplicateErr)
+		assert.Equal(t, []string{"w"}, duplicateErr.Columns)
+		assert.Equal(t, []any{int64(1)}, duplicateErr.Values)
+	})
+}
+
+func TestUpsertClause(t *testing.T) {
+	assert.Equal(t,
+		`ON CONFLICT ("a", "b") DO UPDATE SET "c" = EXCLUDED."c", "d" = EXCLUDED."d" WHERE t.version < EXCLUDED.version`,
+		makeUpsertClause([]string{"a", "b"}, []string{"c", "d"}, "t.version < EXCLUDED.version"),
+	)
+
+	assert.Equal(t, `ON CONFLICT ("a") DO NOTHING`, makeUpsertClause([]string{"a"}, nil, ""))
+}
+
+func TestDuplicateConflictKeyValidator(t *testing.T) {
+	validate := newDuplicateConflictKeyValidator([]string{"a", "b", "c"}, []string{"c", "a"})
+
+	require.NoError(t, validate([]any{1, "x", "k", 1, "x", "l", 2, "x", "k"}))
+
+	one := 1
+	err := validate([]any{1, "x", "k", 2, "x", "k", &one, "y", "k"})
+
+	var duplicateErr *BatchDuplicateConflictKeyError
+	require.ErrorAs(t, err, &duplicateErr)
+	assert.Equal(t, []any{"k", int64(1)}, duplicateErr.Values)
+	assert.EqualError(t, err, "batch contains multiple rows with conflict key (c, a) = [k 1]")
+
+	now := time.Now()
+	require.ErrorAs(t, validate([]any{1, "x", "k", 1, "y", []byte("k")}), &duplicateErr)
+	require.ErrorAs(t, validate([]any{now, "x", "k", now.In(time.FixedZone("test", 3600)), "y", "k"}), &duplicateErr)
+
+	// NULLs never conflict
+	var nilPointer *int
+	require.NoError(t, validate([]any{nil, "x", "k", nil, "y", "k", nilPointer, "z", "k"}))
+}
+
+func TestBatchWriterDiscardsRejectedBatch(t *testing.T) {
+	columnNames := []string{"a", "b"}
+	newQueryBuilder := func(ctx context.Context) (*batchQueryBuilder, error) {
+		return newBatchQueryBuilder("test", columnNames, make([]string, len(columnNames)), "", ""), nil
+	}
+
+	writer := newBatchWriter(nil, len(columnNames), newQueryBuilder, nil)
+	writer.validate = newDuplicateConflictKeyValidator(columnNames, []string{"a"})
+
+	ctx := context.Background()
+	require.NoError(t, writer.write(ctx, []any{1, "x"}))
+	require.NoError(t, writer.write(ctx, []any{1, "y"}))
+
+	var duplicateErr *BatchDuplicateConflictKeyError
+	require.ErrorAs(t, writer.Flush(ctx), &duplicateErr)
+	assert.Empty(t, writer.values)
+
+	// Later flushes are not blocked by the rejected rows
+	require.NoError(t, writer.Flush(ctx))
+}
+
+func TestBatchInserterWithConcurrency(t *testing.T) {
+	t.Run("returning", func(t *testing.T) {
+		var (
+			db          = NewTestDB(t)
+			numRows     = 100000
+			numPayloads = 100
+			columns     = []string{"w", "x", "y", "z", "q", "payload"}
+			collector   = NewCollector(NewAnyValueScanner[int]())
+		)
+
+		setupTestBatchTable(t, db)
+		payloads := createBatchPayloads(t, numPayloads)
+		rowValues := createBatchRowValues(t, numRows, payloads)
+
+		expectedValues := make([]int, 0, numRows)
+		for i := range rowValues {
+			expectedValues = append(expectedValues, i+1)
+		}
+
+		// Insert rows and assert scanned serial ids (in any order)
+		inserter := NewBatchInserter(db, "test", columns, WithBatchInserterConcurrency(4), WithBatchInserterReturn([]string{"id"}, collector.Scanner()))
+		runBatchInserter(t, inserter, rowValues)
+		assert.ElementsMatch(t, expectedValues, collector.Slice())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var (
+			db          = NewTestDB(t)
+			ctx         = context.Background()
+			numRows     = 100000
+			numPayloads = 100
+			columns     = []string{"w", "x", "y", "z", "q", "payload"}
+		)
+
+		setupTestBatchTable(t, db)
+		payloads := createBatchPayloads(t, numPayloads)
+		rowValues := createBatchRowValues(t, numRows, payloads)
+
+		// Insert each row twice so that every batch after the first few conflicts
+		inserter := NewBatchInserter(db, "test", columns, WithBatchInserterConcurrency(4))
+		for _, values := range append(rowValues, rowValues...) {
+			require.NoError(t, inserter.Insert(ctx, values...))
+		}
+
+		err := inserter.Flush(ctx)
+		require.ErrorContains(t, err, "duplicate key value violates unique constraint")
+
+		// Errors are reported once
+		require.NoError(t, inserter.Flush(ctx))
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		var (
+			db          = NewTestDB(t)
+			numRows     = 100000
+			numPayloads = 100
+			columns     = []string{"w", "x", "y", "z", "q", "payload"}
+		)
+
+		setupTestBatchTable(t, db)
+		payloads := createBatchPayloads(t, numPayloads)
+		rowValues := createBatchRowValues(t, numRows, payloads)
+
+		expectedValues := make([]any, 0, numRows)
+		for _, values := range rowValues {
+			expectedValues = append(expectedValues, values[0])
+		}
+
+		// Insert rows serially within a transaction
+		require.NoError(t, db.WithTransaction(context.Background(), func(tx DB) error {
+			inserter := NewBatchInserter(tx, "test", columns, WithBatchInserterConcurrency(4))
+			runBatchInserter(t, inserter, rowValues)
+			return nil
+		}))
+		assertBatchInsertedValues(t, db, "w", expectedValues)
+	})
+}
@@ -0,0 +1,131 @@
+package pgutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type BatchUpdater struct {
+	*batchWriter
+}
+
+// NewBatchUpdater creates a batch writer that updates the given columns of rows
+// matching the given key columns. Values are supplied to Update as key column values
+// followed by update column values. The types of the target columns are read from
+// the database on first flush so that the placeholders of the VALUES list can be
+// cast explicitly.
+func NewBatchUpdater(db DB, tableName string, keyColumnNames, updateColumnNames []string, configs ...BatchUpdaterConfigFunc) *BatchUpdater {
+	var (
+		options     = getBatchUpdaterOptions(configs)
+		columnNames = append(append([]string(nil), keyColumnNames...), updateColumnNames...)
+	)
+
+	newQueryBuilder := func(ctx context.Context) (*batchQueryBuilder, error) {
+		columnTypes, err := describeBatchColumnTypes(ctx, db, tableName, columnNames)
+		if err != nil {
+			return nil, err
+		}
+
+		return newBatchUpdateQueryBuilder(tableName, keyColumnNames, updateColumnNames, columnTypes, options.returningColumns), nil
+	}
+
+	return &BatchUpdater{
+		batchWriter: newBatchWriter(db, len(columnNames), newQueryBuilder, options.returningScanner),
+	}
+}
+
+func (u *BatchUpdater) Update(ctx context.Context, values ...any) error {
+	return u.write(ctx, values)
+}
+
+const batchUpdateValuesAlias = "v"
+
+func newBatchUpdateQueryBuilder(tableName string, keyColumnNames, updateColumnNames, columnTypes, returningColumns []string) *batchQueryBuilder {
+	var (
+		quotedTableName = fmt.Sprintf("%q", tableName)
+		columnNames     = append(append([]string(nil), keyColumnNames...), updateColumnNames...)
+		assignments     = make([]string, 0, len(updateColumnNames))
+		conditions      = make([]string, 0, len(keyColumnNames))
+	)
+
+	for _, name := range updateColumnNames {
+		assignments = append(assignments, fmt.Sprintf("%s = %s.%s", quoteColumnName(name), batchUpdateValuesAlias, quoteColumnName(name)))
+	}
+	for _, name := range keyColumnNames {
+		conditions = append(conditions, fmt.Sprintf("%s.%s = %s.%s", quotedTableName, quoteColumnName(name), batchUpdateValuesAlias, quoteColumnName(name)))
+	}
+
+	var returningClause string
+	if len(returningColumns) > 0 {
+		qualified := make([]string, 0, len(returningColumns))
+		for _, name := range returningColumns {
+			// Qualify with the table name; the values list may share column names
+			qualified = append(qualified, fmt.Sprintf("%s.%s", quotedTableName, quoteColumnName(name)))
+		}
+
+		returningClause = fmt.Sprintf("RETURNING %s", strings.Join(qualified, ", "))
+	}
+
+	var (
+		queryPrefix = fmt.Sprintf("UPDATE %s SET %s FROM (VALUES", quotedTableName, strings.Join(assignments, ", "))
+		querySuffix = fmt.Sprintf(") AS %s (%s) WHERE %s %s",
+			batchUpdateValuesAlias,
+			strings.Join(quoteColumnNames(columnNames), ", "),
+			strings.Join(conditions, " AND "),
+			returningClause,
+		)
+	)
+
+	return newBatchQueryBuilderFromParts(queryPrefix, querySuffix, len(columnNames), columnTypes)
+}
+
+type batchColumnType struct {
+	Name string
+	Type string
+}
+
+var scanBatchColumnTypes = NewSliceScanner(func(s Scanner) (c batchColumnType, _ error) {
+	err := s.Scan(&c.Name, &c.Type)
+	return c, err
+})
+
+// describeBatchColumnTypes returns the types of the given columns of the given table
+// in the same order, suitable for use in a cast. The table is resolved with the same
+// search path as the batch queries that reference it.
+//
+// NOTE: Types are described without their modifiers (e.g., `character varying` rather
+// than `character varying(10)`). An explicit cast to a length-constrained type truncates
+// over-length values silently, whereas assigning to the column raises an error.
+func describeBatchColumnTypes(ctx context.Context, db DB, tableName string, columnNames []string) ([]string, error) {
+	columns, err := scanBatchColumnTypes(db.Query(ctx, Query(`
+		SELECT a.attname, format_type(a.atttypid, NULL)
+		FROM pg_catalog.pg_attribute a
+		WHERE a.attrelid = to_regclass({:table}) AND a.attnum > 0 AND NOT a.attisdropped
+	`, Args{
+		"table": fmt.Sprintf("%q", tableName),
+	})))
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %q does not exist", tableName)
+	}
+
+	typesByName := map[string]string{}
+	for _, column := range columns {
+		typesByName[column.Name] = column.Type
+	}
+
+	columnTypes := make([]string, 0, len(columnNames))
+	for _, name := range columnNames {
+		columnType, ok := typesByName[name]
+		if !ok {
+			return nil, fmt.Errorf("column %q does not exist in table %q", name, tableName)
+		}
+
+		columnTypes = append(columnTypes, columnType)
+	}
+
+	return columnTypes, nil
+}
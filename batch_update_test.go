@@ -0,0 +1,92 @@
+package pgutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchUpdater(t *testing.T) {
+	var (
+		db          = NewTestDB(t)
+		ctx         = context.Background()
+		numRows     = 100000
+		numPayloads = 100
+		columns     = []string{"w", "x", "y", "z", "q", "payload"}
+	)
+
+	setupTestBatchTable(t, db)
+	payloads := createBatchPayloads(t, numPayloads)
+	rowValues := createBatchRowValues(t, numRows, payloads)
+	runBatchInserter(t, NewBatchInserter(db, "test", columns), rowValues)
+
+	// Update every other row keyed on column "w"
+	expectedValues := make([]any, 0, numRows)
+	updater := NewBatchUpdater(db, "test", []string{"w"}, []string{"x", "payload"})
+	for i, values := range rowValues {
+		if i%2 == 0 {
+			require.NoError(t, updater.Update(ctx, values[0], int64(-i), "updated"))
+			expectedValues = append(expectedValues, int64(-i))
+		} else {
+			expectedValues = append(expectedValues, values[1])
+		}
+	}
+	require.NoError(t, updater.Flush(ctx))
+
+	assertBatchInsertedValues(t, db, "x", expectedValues)
+}
+
+func TestBatchUpdaterWithReturning(t *testing.T) {
+	var (
+		db        = NewTestDB(t)
+		ctx       = context.Background()
+		columns   = []string{"w", "x", "y", "z", "q", "payload"}
+		collector = NewCollector(NewAnyValueScanner[int]())
+	)
+
+	setupTestBatchTable(t, db)
+	payloads := createBatchPayloads(t, 10)
+	rowValues := createBatchRowValues(t, 100, payloads)
+	runBatchInserter(t, NewBatchInserter(db, "test", columns), rowValues)
+
+	// Update rows keyed on columns "w" and "x" and assert scanned serial ids
+	updater := NewBatchUpdater(db, "test", []string{"w", "x"}, []string{"y"}, WithBatchUpdaterReturn([]string{"id"}, collector.Scanner()))
+	require.NoError(t, updater.Update(ctx, rowValues[3][0], rowValues[3][1], int64(0)))
+	require.NoError(t, updater.Update(ctx, rowValues[5][0], rowValues[5][1], int64(0)))
+	require.NoError(t, updater.Update(ctx, rowValues[7][0], int64(-1), int64(0))) // no match
+	require.NoError(t, updater.Flush(ctx))
+
+	assert.ElementsMatch(t, []int{4, 6}, collector.Slice())
+}
+
+func TestBatchUpdaterLengthConstrainedColumns(t *testing.T) {
+	var (
+		db  = NewTestDB(t)
+		ctx = context.Background()
+	)
+
+	require.NoError(t, db.Exec(ctx, RawQuery(`CREATE TABLE constrained (id integer PRIMARY KEY, code varchar(3) NOT NULL)`)))
+	require.NoError(t, db.Exec(ctx, RawQuery(`INSERT INTO constrained VALUES (1, 'abc')`)))
+
+	// Over-length values are rejected rather than truncated by a cast
+	updater := NewBatchUpdater(db, "constrained", []string{"id"}, []string{"code"})
+	require.NoError(t, updater.Update(ctx, 1, "abcdef"))
+	require.ErrorContains(t, updater.Flush(ctx), "value too long for type character varying(3)")
+
+	code, _, err := ScanString(db.Query(ctx, RawQuery(`SELECT code FROM constrained WHERE id = 1`)))
+	require.NoError(t, err)
+	assert.Equal(t, "abc", code)
+}
+
+func TestBatchUpdateQueryBuilder(t *testing.T) {
+	builder := newBatchUpdateQueryBuilder("test", []string{"id"}, []string{"x", "y"}, []string{"integer", "text", "my_enum"}, []string{"id"})
+
+	assert.Equal(t,
+		`UPDATE "test" SET "x" = v."x", "y" = v."y" FROM (VALUES `+
+			`($00001::integer,$00002::text,$00003::my_enum),($00004::integer,$00005::text,$00006::my_enum) `+
+			`) AS v ("id", "x", "y") WHERE "test"."id" = v."id" RETURNING "test"."id"`,
+		builder.build(6),
+	)
+}
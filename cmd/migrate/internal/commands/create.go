@@ -5,7 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-nacelle/log/v2"
 	"github.com/go-nacelle/pgutil"
@@ -16,6 +18,7 @@ import (
 func CreateCommand(logger log.Logger) *cobra.Command {
 	var (
 		migrationsDirectory string
+		idFormat            string
 	)
 
 	createCmd := &cobra.Command{
@@ -23,15 +26,27 @@ func CreateCommand(logger log.Logger) *cobra.Command {
 		Short: "Create a new schema migration",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return create(migrationsDirectory, args[0])
+			return create(migrationsDirectory, idFormat, args[0], time.Now())
 		},
 	}
 
 	flags.RegisterMigrationsDirectoryFlag(createCmd, &migrationsDirectory)
+	createCmd.Flags().StringVarP(
+		&idFormat,
+		"id-format", "",
+		idFormatSequential,
+		fmt.Sprintf("The format of the new migration's ID (%s, %s, or %s)", idFormatSequential, idFormatUnix, idFormatTimestamp),
+	)
 	return createCmd
 }
 
-func create(migrationsDirectory string, name string) error {
+const (
+	idFormatSequential = "sequential" // one greater than the largest existing ID
+	idFormatUnix       = "unix"       // seconds since the Unix epoch
+	idFormatTimestamp  = "timestamp"  // YYYYMMDDHHMMSS in UTC
+)
+
+func create(migrationsDirectory, idFormat, name string, now time.Time) error {
 	if err := ensureMigrationDirectoryExists(migrationsDirectory); err != nil {
 		return err
 	}
@@ -41,12 +56,26 @@ func create(migrationsDirectory string, name string) error {
 		return err
 	}
 
+	// NOTE: Definitions are in topological order, which need not be ordered by ID
 	var lastID int
-	if len(definitions) > 0 {
-		lastID = definitions[len(definitions)-1].ID
+	for _, definition := range definitions {
+		if definition.ID > lastID {
+			lastID = definition.ID
+		}
 	}
 
-	dirPath := filepath.Join(migrationsDirectory, fmt.Sprintf("%d_%s", lastID+1, canonicalize(name)))
+	id, err := nextMigrationID(idFormat, lastID, now)
+	if err != nil {
+		return err
+	}
+
+	for _, definition := range definitions {
+		if definition.ID == id {
+			return fmt.Errorf("migration %d already exists", id)
+		}
+	}
+
+	dirPath := filepath.Join(migrationsDirectory, fmt.Sprintf("%d_%s", id, canonicalize(name)))
 	upPath := filepath.Join(dirPath, "up.sql")
 	downPath := filepath.Join(dirPath, "down.sql")
 
@@ -63,6 +92,24 @@ func create(migrationsDirectory string, name string) error {
 	return nil
 }
 
+// nextMigrationID returns the ID of a new migration. Time-based IDs allow migrations
+// created concurrently on separate branches to receive distinct IDs.
+func nextMigrationID(idFormat string, lastID int, now time.Time) (int, error) {
+	switch idFormat {
+	case idFormatSequential:
+		return lastID + 1, nil
+
+	case idFormatUnix:
+		return int(now.Unix()), nil
+
+	case idFormatTimestamp:
+		return strconv.Atoi(now.UTC().Format("20060102150405"))
+
+	default:
+		return 0, fmt.Errorf("unknown ID format %q", idFormat)
+	}
+}
+
 func ensureMigrationDirectoryExists(migrationDirectory string) error {
 	stat, err := os.Stat(migrationDirectory)
 	if err != nil {
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-nacelle/pgutil"
+)
+
+// printPlan prints the SQL of the migrations that would be applied or undone for the
+// given target without applying them.
+func printPlan(runner *pgutil.Runner, target pgutil.MigrationTarget) error {
+	plan, err := runner.Plan(context.Background(), target)
+	if err != nil {
+		return err
+	}
+
+	if len(plan) == 0 {
+		fmt.Println("-- Migrations are in expected state")
+		return nil
+	}
+
+	for _, migration := range plan {
+		direction := "up"
+		if migration.Reverse {
+			direction = "down"
+		}
+
+		var notes []string
+		if migration.ConcurrentIndexCreation {
+			notes = append(notes, "concurrent index creation")
+		}
+		if migration.NoTransaction {
+			notes = append(notes, "no transaction")
+		}
+
+		header := fmt.Sprintf("-- %04d: %s (%s)", migration.Definition.ID, migration.Definition.Name, direction)
+		if len(notes) > 0 {
+			header += fmt.Sprintf(" [%s]", strings.Join(notes, ", "))
+		}
+
+		query, _ := migration.Query.Format()
+		fmt.Printf("%s\n%s\n\n", header, strings.TrimSpace(query))
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-nacelle/log/v2"
+	"github.com/go-nacelle/pgutil"
+	"github.com/go-nacelle/pgutil/cmd/migrate/internal/database"
+	"github.com/go-nacelle/pgutil/cmd/migrate/internal/flags"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func SquashCommand(logger log.Logger) *cobra.Command {
+	var (
+		databaseURL         string
+		migrationsDirectory string
+	)
+
+	squashCmd := &cobra.Command{
+		Use:   "squash <migration-id>",
+		Short: "Replace migrations up to and including the specified migration ID with a baseline migration",
+		Long:  "Replace migrations up to and including the specified migration ID with a baseline migration. The migrations are applied to a scratch database created on the server of the given database URL.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			migrationID, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid migration ID: %v", err)
+			}
+
+			return squash(databaseURL, migrationsDirectory, logger, migrationID)
+		},
+	}
+
+	flags.RegisterDatabaseURLFlag(squashCmd, &databaseURL)
+	flags.RegisterMigrationsDirectoryFlag(squashCmd, &migrationsDirectory)
+	return squashCmd
+}
+
+// baselineMetadata is written to the metadata.yaml file of a baseline migration.
+type baselineMetadata struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Squashes    []int  `yaml:"squashes,flow"`
+}
+
+func squash(databaseURL, migrationsDirectory string, logger log.Logger, migrationID int) (err error) {
+	db, drop, err := database.CreateScratchDatabase(databaseURL, logger)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, drop()) }()
+
+	reader := pgutil.NewFilesystemMigrationReader(migrationsDirectory)
+	baseline, err := pgutil.SquashMigrations(context.Background(), db, reader, migrationID, logger)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := yaml.Marshal(baselineMetadata{
+		Name:        baseline.Name,
+		Description: baseline.Description,
+		Squashes:    baseline.Squashes,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write the baseline into a temporary directory first so that a failure leaves
+	// the existing migrations untouched
+	tempDir, err := os.MkdirTemp(migrationsDirectory, ".squash-")
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, os.RemoveAll(tempDir)) }()
+
+	baselinePath := filepath.Join(tempDir, "baseline")
+	if err := os.Mkdir(baselinePath, os.ModePerm); err != nil {
+		return err
+	}
+
+	for filename, contents := range map[string][]byte{
+		"up.sql":        []byte(baseline.RawUpQuery),
+		"down.sql":      []byte(baseline.RawDownQuery),
+		"metadata.yaml": metadata,
+	} {
+		if err := os.WriteFile(filepath.Join(baselinePath, filename), contents, os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	// Move the squashed migrations aside (rather than deleting them outright) so that
+	// they can be restored if the baseline cannot be moved into place. This also frees
+	// the baseline's directory name if a squashed migration already uses it.
+	squashedPath := filepath.Join(tempDir, "squashed")
+	if err := os.Mkdir(squashedPath, os.ModePerm); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(migrationsDirectory)
+	if err != nil {
+		return err
+	}
+
+	squashed := map[string]struct{}{}
+	for _, id := range baseline.Squashes {
+		squashed[strconv.Itoa(id)] = struct{}{}
+	}
+
+	var moved []string
+	restore := func() error {
+		var errs []error
+		for _, name := range moved {
+			errs = append(errs, os.Rename(filepath.Join(squashedPath, name), filepath.Join(migrationsDirectory, name)))
+		}
+
+		return errors.Join(errs...)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		if _, ok := squashed[strings.SplitN(entry.Name(), "_", 2)[0]]; ok {
+			if err := os.Rename(filepath.Join(migrationsDirectory, entry.Name()), filepath.Join(squashedPath, entry.Name())); err != nil {
+				return errors.Join(err, restore())
+			}
+
+			moved = append(moved, entry.Name())
+		}
+	}
+
+	dirPath := filepath.Join(migrationsDirectory, fmt.Sprintf("%d_%s", baseline.ID, canonicalize(baseline.Name)))
+	if err := os.Rename(baselinePath, dirPath); err != nil {
+		return errors.Join(err, restore())
+	}
+
+	fmt.Printf("Squashed %d migrations into %s\n", len(baseline.Squashes), dirPath)
+	return nil
+}
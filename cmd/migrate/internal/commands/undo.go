@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/go-nacelle/log/v2"
+	"github.com/go-nacelle/pgutil"
 	"github.com/go-nacelle/pgutil/cmd/migrate/internal/database"
 	"github.com/go-nacelle/pgutil/cmd/migrate/internal/flags"
 	"github.com/spf13/cobra"
@@ -15,6 +16,7 @@ func UndoCommand(logger log.Logger) *cobra.Command {
 	var (
 		databaseURL         string
 		migrationsDirectory string
+		dryRun              bool
 	)
 
 	undoCmd := &cobra.Command{
@@ -27,20 +29,25 @@ func UndoCommand(logger log.Logger) *cobra.Command {
 				return fmt.Errorf("invalid migration ID: %v", err)
 			}
 
-			return undo(databaseURL, migrationsDirectory, logger, migrationID)
+			return undo(databaseURL, migrationsDirectory, logger, migrationID, dryRun)
 		},
 	}
 
 	flags.RegisterDatabaseURLFlag(undoCmd, &databaseURL)
 	flags.RegisterMigrationsDirectoryFlag(undoCmd, &migrationsDirectory)
+	undoCmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "Print the SQL that would run without running it")
 	return undoCmd
 }
 
-func undo(databaseURL string, migrationsDirectory string, logger log.Logger, migrationID int) error {
+func undo(databaseURL string, migrationsDirectory string, logger log.Logger, migrationID int, dryRun bool) error {
 	runner, err := database.CreateRunner(databaseURL, migrationsDirectory, logger)
 	if err != nil {
 		return err
 	}
 
+	if dryRun {
+		return printPlan(runner, pgutil.UndoTarget(migrationID))
+	}
+
 	return runner.Undo(context.Background(), migrationID)
 }
@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/go-nacelle/log/v2"
+	"github.com/go-nacelle/pgutil"
 	"github.com/go-nacelle/pgutil/cmd/migrate/internal/database"
 	"github.com/go-nacelle/pgutil/cmd/migrate/internal/flags"
 	"github.com/spf13/cobra"
@@ -15,6 +16,7 @@ func UpCommand(logger log.Logger) *cobra.Command {
 	var (
 		databaseURL         string
 		migrationsDirectory string
+		dryRun              bool
 	)
 
 	upCmd := &cobra.Command{
@@ -31,21 +33,31 @@ func UpCommand(logger log.Logger) *cobra.Command {
 				migrationID = &val
 			}
 
-			return up(databaseURL, migrationsDirectory, logger, migrationID)
+			return up(databaseURL, migrationsDirectory, logger, migrationID, dryRun)
 		},
 	}
 
 	flags.RegisterDatabaseURLFlag(upCmd, &databaseURL)
 	flags.RegisterMigrationsDirectoryFlag(upCmd, &migrationsDirectory)
+	upCmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "Print the SQL that would run without running it")
 	return upCmd
 }
 
-func up(databaseURL, migrationsDirectory string, logger log.Logger, migrationID *int) error {
+func up(databaseURL, migrationsDirectory string, logger log.Logger, migrationID *int, dryRun bool) error {
 	runner, err := database.CreateRunner(databaseURL, migrationsDirectory, logger)
 	if err != nil {
 		return err
 	}
 
+	if dryRun {
+		target := pgutil.ApplyAllTarget()
+		if migrationID != nil {
+			target = pgutil.ApplyTarget(*migrationID)
+		}
+
+		return printPlan(runner, target)
+	}
+
 	if migrationID == nil {
 		return runner.ApplyAll(context.Background())
 	}
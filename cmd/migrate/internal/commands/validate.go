@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-nacelle/log/v2"
+	"github.com/go-nacelle/pgutil/cmd/migrate/internal/database"
+	"github.com/go-nacelle/pgutil/cmd/migrate/internal/flags"
+	"github.com/spf13/cobra"
+)
+
+func ValidateCommand(logger log.Logger) *cobra.Command {
+	var (
+		databaseURL         string
+		migrationsDirectory string
+	)
+
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check that the defined migrations match the database and can be applied in order",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return validate(databaseURL, migrationsDirectory, logger)
+		},
+	}
+
+	flags.RegisterDatabaseURLFlag(validateCmd, &databaseURL)
+	flags.RegisterMigrationsDirectoryFlag(validateCmd, &migrationsDirectory)
+	return validateCmd
+}
+
+func validate(databaseURL, migrationsDirectory string, logger log.Logger) error {
+	// NOTE: Duplicate IDs, unknown parents, and cycles are detected on construction
+	runner, err := database.CreateRunner(databaseURL, migrationsDirectory, logger)
+	if err != nil {
+		return err
+	}
+
+	modified, err := runner.ModifiedMigrations(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if len(modified) > 0 {
+		fmt.Println("Migrations modified since they were applied:")
+
+		for _, definition := range modified {
+			fmt.Printf("  %04d: %s\n", definition.ID, definition.Name)
+		}
+
+		return fmt.Errorf("found %d modified migrations", len(modified))
+	}
+
+	outOfOrder, err := runner.OutOfOrderMigrations(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if len(outOfOrder) > 0 {
+		fmt.Println("Migrations not applied but preceding an applied migration:")
+
+		for _, definition := range outOfOrder {
+			fmt.Printf("  %04d: %s\n", definition.ID, definition.Name)
+		}
+
+		return fmt.Errorf("found %d out-of-order migrations", len(outOfOrder))
+	}
+
+	fmt.Println("Migrations are valid")
+	return nil
+}
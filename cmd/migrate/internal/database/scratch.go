@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/go-nacelle/log/v2"
+	"github.com/go-nacelle/pgutil"
+	"github.com/lib/pq"
+)
+
+// CreateScratchDatabase creates an empty database on the server of the given database
+// URL and returns a connection to it. The returned function drops the database.
+func CreateScratchDatabase(databaseURL string, logger log.Logger) (_ pgutil.DB, drop func() error, _ error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, nil, err
+	}
+	name := fmt.Sprintf("pgutil-scratch-%s", hex.EncodeToString(suffix))
+
+	parsedURL, err := url.Parse(databaseURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	scratchURL := parsedURL.ResolveReference(&url.URL{
+		Path:     "/" + name,
+		RawQuery: parsedURL.RawQuery,
+	})
+
+	db, err := Dial(databaseURL, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// NOTE: Must interpolate identifiers here as placeholders aren't valid in this position.
+	if err := db.Exec(context.Background(), pgutil.RawQuery(fmt.Sprintf("CREATE DATABASE %s TEMPLATE template0", pq.QuoteIdentifier(name)))); err != nil {
+		return nil, nil, err
+	}
+
+	drop = func() error {
+		if err := db.Exec(context.Background(), pgutil.Query(
+			"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = {:name}",
+			pgutil.Args{"name": name},
+		)); err != nil {
+			return err
+		}
+
+		return db.Exec(context.Background(), pgutil.RawQuery(fmt.Sprintf("DROP DATABASE %s", pq.QuoteIdentifier(name))))
+	}
+
+	scratchDB, err := Dial(scratchURL.String(), logger)
+	if err != nil {
+		return nil, nil, errors.Join(err, drop())
+	}
+
+	return scratchDB, drop, nil
+}
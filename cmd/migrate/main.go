@@ -26,6 +26,8 @@ func init() {
 	rootCmd.AddCommand(commands.WriteMigrationLogCommand(logger))
 	rootCmd.AddCommand(commands.DescribeCommand(logger))
 	rootCmd.AddCommand(commands.DriftCommand(logger))
+	rootCmd.AddCommand(commands.ValidateCommand(logger))
+	rootCmd.AddCommand(commands.SquashCommand(logger))
 }
 
 func main() {
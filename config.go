@@ -1,6 +1,8 @@
 package pgutil
 
 type Config struct {
-	DatabaseURL   string `env:"database_url" required:"true"`
-	LogSQLQueries bool   `env:"log_sql_queries" default:"false"`
+	DatabaseURL                string `env:"database_url" required:"true"`
+	LogSQLQueries              bool   `env:"log_sql_queries" default:"false"`
+	LogSQLDebugQueries         bool   `env:"log_sql_debug_queries" default:"false"`
+	PreparedStatementCacheSize int    `env:"prepared_statement_cache_size" default:"0"`
 }
@@ -3,6 +3,7 @@ package pgutil
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"time"
@@ -11,7 +12,7 @@ import (
 )
 
 type DB interface {
-	Query(ctx context.Context, query Q) (*sql.Rows, error)
+	Query(ctx context.Context, query Q) (Rows, error)
 	Exec(ctx context.Context, query Q) error
 	WithTransaction(ctx context.Context, f func(tx DB) error) error
 
@@ -22,13 +23,24 @@ type DB interface {
 
 type loggingDB struct {
 	*queryWrapper
-	db *sql.DB
+	db    *sql.DB
+	stmts *stmtCache
 }
 
-func newLoggingDB(db *sql.DB, logger nacelle.Logger) *loggingDB {
+func newLoggingDB(db *sql.DB, logger nacelle.Logger, configs ...DialConfigFunc) *loggingDB {
+	var (
+		options = getDialOptions(configs)
+		stmts   *stmtCache
+	)
+
+	if options.preparedStatementCacheSize > 0 {
+		stmts = newStmtCache(db, options.preparedStatementCacheSize)
+	}
+
 	return &loggingDB{
-		queryWrapper: newDBWrapper(db, logger),
+		queryWrapper: newDBWrapper(db, logger, options, stmts),
 		db:           db,
+		stmts:        stmts,
 	}
 }
 
@@ -45,11 +57,11 @@ func (db *loggingDB) Transact(ctx context.Context) (DB, error) {
 
 	tx, err := db.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return nil, db.translateError(err)
 	}
 
 	return &loggingTx{
-		queryWrapper: newTxWrapper(tx, db.logger),
+		queryWrapper: newTxWrapper(tx, db.logger, db.options, db.stmts),
 		tx:           tx,
 		start:        start,
 	}, nil
@@ -60,3 +72,34 @@ var ErrNotInTransaction = fmt.Errorf("not in a transaction")
 func (db *loggingDB) Done(err error) error {
 	return errors.Join(err, ErrNotInTransaction)
 }
+
+// pinConn reserves a single connection from the pool. Session-level state (e.g.,
+// advisory locks taken with pg_advisory_lock) is scoped to this connection.
+func (db *loggingDB) pinConn(ctx context.Context) (*pinnedConn, error) {
+	conn, err := db.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pinnedConn{
+		queryWrapper: newConnWrapper(conn, db.logger, db.options),
+		conn:         conn,
+	}, nil
+}
+
+type pinnedConn struct {
+	*queryWrapper
+	conn *sql.Conn
+}
+
+// release returns the connection to the pool.
+func (c *pinnedConn) release() error {
+	return c.conn.Close()
+}
+
+// discard closes the underlying connection rather than returning it to the pool.
+// Postgres releases all session-level state once the connection is closed.
+func (c *pinnedConn) discard() {
+	_ = c.conn.Raw(func(driverConn any) error { return driver.ErrBadConn })
+	_ = c.conn.Close()
+}
@@ -0,0 +1,171 @@
+package pgutil
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Prepared marks the query as a candidate for the prepared statement cache. This
+// has no effect unless the connection was dialed with WithDialPreparedStatementCache.
+func (q Q) Prepared() Q {
+	q.prepared = true
+	return q
+}
+
+// stmtCache holds prepared statements keyed on formatted SQL. Statements prepared
+// on a *sql.DB are transparently re-prepared by database/sql on each underlying
+// connection on which they are used, so one entry serves the entire pool.
+type stmtCache struct {
+	db    *sql.DB
+	mu    sync.Mutex
+	stmts *lruCache[string, *cachedStmt]
+}
+
+type cachedStmt struct {
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(db *sql.DB, capacity int) *stmtCache {
+	return &stmtCache{
+		db: db,
+		stmts: newLRUCache(capacity, func(_ string, cached *cachedStmt) {
+			cached.evicted = true
+			cached.closeIfUnused()
+		}),
+	}
+}
+
+func (c *stmtCache) prepare(ctx context.Context, query string) (*sql.Stmt, func(), error) {
+	c.mu.Lock()
+	cached, ok := c.stmts.get(query)
+	if !ok {
+		// Prepare without holding the lock so that a slow round-trip does not block
+		// callers using statements that are already cached.
+		c.mu.Unlock()
+		stmt, err := c.db.PrepareContext(ctx, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.mu.Lock()
+
+		if cached, ok = c.stmts.get(query); ok {
+			// Another goroutine prepared the same query concurrently
+			_ = stmt.Close()
+		} else {
+			cached = &cachedStmt{stmt: stmt}
+			c.stmts.put(query, cached)
+		}
+	}
+	defer c.mu.Unlock()
+
+	// Hold a reference so that a concurrent eviction does not close the statement
+	// before the caller has used it. Once used, database/sql itself defers closing
+	// until any resulting rows (or transaction-bound statements) are closed.
+	cached.refs++
+
+	release := func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		cached.refs--
+		cached.closeIfUnused()
+	}
+
+	return cached.stmt, release, nil
+}
+
+func (s *cachedStmt) closeIfUnused() {
+	if s.evicted && s.refs == 0 {
+		_ = s.stmt.Close()
+	}
+}
+
+// txStmtCache binds statements from the shared cache to a single transaction.
+// Transaction-bound statements are closed by database/sql on commit or rollback.
+type txStmtCache struct {
+	cache *stmtCache
+	tx    *sql.Tx
+	stmts map[string]*sql.Stmt
+}
+
+func newTxStmtCache(cache *stmtCache, tx *sql.Tx) *txStmtCache {
+	return &txStmtCache{
+		cache: cache,
+		tx:    tx,
+		stmts: map[string]*sql.Stmt{},
+	}
+}
+
+func (c *txStmtCache) prepare(ctx context.Context, query string) (*sql.Stmt, func(), error) {
+	if stmt, ok := c.stmts[query]; ok {
+		return stmt, func() {}, nil
+	}
+
+	stmt, release, err := c.cache.prepare(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	txStmt := c.tx.StmtContext(ctx, stmt)
+	c.stmts[query] = txStmt
+	return txStmt, func() {}, nil
+}
+
+//
+//
+
+type lruCache[K comparable, V any] struct {
+	capacity int
+	order    *list.List
+	entries  map[K]*list.Element
+	onEvict  func(K, V)
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func newLRUCache[K comparable, V any](capacity int, onEvict func(K, V)) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  map[K]*list.Element{},
+		onEvict:  onEvict,
+	}
+}
+
+func (c *lruCache[K, V]) get(key K) (value V, _ bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return value, false
+	}
+
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry[K, V]).value, true
+}
+
+func (c *lruCache[K, V]) put(key K, value V) {
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		entry := c.order.Remove(oldest).(*lruEntry[K, V])
+		delete(c.entries, entry.key)
+
+		if c.onEvict != nil {
+			c.onEvict(entry.key, entry.value)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package pgutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/go-nacelle/log/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparedStatementCache(t *testing.T) {
+	db := NewTestDBWithLogger(t, log.NewNilLogger(), WithDialPreparedStatementCache(2))
+	setupTestTransactionTable(t, db)
+	ctx := context.Background()
+
+	insert := func(db DB, x, y int) {
+		require.NoError(t, db.Exec(ctx, Query("INSERT INTO test (x, y) VALUES ({:x}, {:y})", Args{"x": x, "y": y}).Prepared()))
+	}
+
+	// Outside of a transaction
+	insert(db, 1, 42)
+	insert(db, 2, 43)
+
+	// Inside of a transaction (and savepoint)
+	require.NoError(t, db.WithTransaction(ctx, func(tx DB) error {
+		insert(tx, 3, 44)
+
+		return tx.WithTransaction(ctx, func(tx DB) error {
+			insert(tx, 4, 45)
+			return nil
+		})
+	}))
+
+	// Force evictions
+	for i := 0; i < 5; i++ {
+		_, _, err := ScanInt(db.Query(ctx, queryf("SELECT %d", i).Prepared()))
+		require.NoError(t, err)
+	}
+	insert(db, 5, 46)
+
+	assert.Equal(t, map[int]int{1: 42, 2: 43, 3: 44, 4: 45, 5: 46}, testTableContents(t, db))
+}
+
+func TestPreparedStatementCacheConcurrentPrepare(t *testing.T) {
+	db := NewTestDBWithLogger(t, log.NewNilLogger(), WithDialPreparedStatementCache(2))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			value, _, err := ScanInt(db.Query(ctx, queryf("SELECT %d", i%4).Prepared()))
+			if err == nil && value != i%4 {
+				err = fmt.Errorf("unexpected value %d for query %d", value, i%4)
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	var evicted []string
+	cache := newLRUCache(2, func(key string, _ int) { evicted = append(evicted, key) })
+
+	cache.put("a", 1)
+	cache.put("b", 2)
+
+	// Touch "a" so that "b" is the least recently used
+	value, ok := cache.get("a")
+	require.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	cache.put("c", 3)
+	assert.Equal(t, []string{"b"}, evicted)
+
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+
+	cache.put("a", 4)
+	cache.put("d", 5)
+	assert.Equal(t, []string{"b", "c"}, evicted)
+
+	value, ok = cache.get("a")
+	require.True(t, ok)
+	assert.Equal(t, 4, value)
+}
@@ -33,10 +33,11 @@ func (tx *loggingTx) Done(err error) (combinedErr error) {
 
 	if err != nil {
 		rollbackErr := tx.tx.Rollback()
-		return errors.Join(err, rollbackErr)
+		return errors.Join(err, tx.translateError(rollbackErr))
 	}
 
-	return tx.tx.Commit()
+	// Deferred constraints are checked on commit
+	return tx.translateError(tx.tx.Commit())
 }
 
 type loggingSavepoint struct {
@@ -10,9 +10,11 @@ import (
 )
 
 type queryWrapper struct {
-	db     sqlDB
-	mu     *sync.Mutex
-	logger nacelle.Logger
+	db      sqlDB
+	mu      *sync.Mutex
+	logger  nacelle.Logger
+	options *dialOptions
+	prepare stmtPreparer
 }
 
 type sqlDB interface {
@@ -20,30 +22,63 @@ type sqlDB interface {
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 }
 
-func newDBWrapper(db *sql.DB, logger nacelle.Logger) *queryWrapper {
+type stmtPreparer func(ctx context.Context, query string) (_ *sql.Stmt, release func(), _ error)
+
+func newDBWrapper(db *sql.DB, logger nacelle.Logger, options *dialOptions, stmts *stmtCache) *queryWrapper {
+	var prepare stmtPreparer
+	if stmts != nil {
+		prepare = stmts.prepare
+	}
+
+	return &queryWrapper{
+		db:      db,
+		logger:  logger,
+		options: options,
+		prepare: prepare,
+	}
+}
+
+func newTxWrapper(tx *sql.Tx, logger nacelle.Logger, options *dialOptions, stmts *stmtCache) *queryWrapper {
+	var prepare stmtPreparer
+	if stmts != nil {
+		prepare = newTxStmtCache(stmts, tx).prepare
+	}
+
 	return &queryWrapper{
-		db:     db,
-		logger: logger,
+		db:      tx,
+		mu:      new(sync.Mutex),
+		logger:  logger,
+		options: options,
+		prepare: prepare,
 	}
 }
 
-func newTxWrapper(tx *sql.Tx, logger nacelle.Logger) *queryWrapper {
+func newConnWrapper(conn *sql.Conn, logger nacelle.Logger, options *dialOptions) *queryWrapper {
 	return &queryWrapper{
-		db:     tx,
-		mu:     new(sync.Mutex),
-		logger: logger,
+		db:      conn,
+		mu:      new(sync.Mutex),
+		logger:  logger,
+		options: options,
 	}
 }
 
-func (db *queryWrapper) Query(ctx context.Context, q Q) (*sql.Rows, error) {
+func (db *queryWrapper) Query(ctx context.Context, q Q) (Rows, error) {
 	start := time.Now()
 	db.lock()
 	defer db.unlock()
 
 	query, args := q.Format()
-	rows, err := db.db.QueryContext(ctx, query, args...)
-	logQuery(db.logger, time.Since(start), err, query, args)
-	return rows, err
+	rows, err := db.query(ctx, q.prepared, query, args)
+	logQuery(db.logger, time.Since(start), err, query, args, db.options.logDebugQueries)
+	if err != nil {
+		return nil, db.translateError(err)
+	}
+
+	if db.options.errorRegistry != nil {
+		return &translatingRows{Rows: rows, registry: db.options.errorRegistry}, nil
+	}
+
+	return rows, nil
 }
 
 func (db *queryWrapper) Exec(ctx context.Context, q Q) error {
@@ -52,11 +87,65 @@ func (db *queryWrapper) Exec(ctx context.Context, q Q) error {
 	defer db.unlock()
 
 	query, args := q.Format()
-	_, err := db.db.ExecContext(ctx, query, args...)
-	logQuery(db.logger, time.Since(start), err, query, args)
+	err := db.exec(ctx, q.prepared, query, args)
+	logQuery(db.logger, time.Since(start), err, query, args, db.options.logDebugQueries)
+	return db.translateError(err)
+}
+
+func (db *queryWrapper) query(ctx context.Context, prepared bool, query string, args []any) (*sql.Rows, error) {
+	if !prepared || db.prepare == nil {
+		return db.db.QueryContext(ctx, query, args...)
+	}
+
+	stmt, release, err := db.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return stmt.QueryContext(ctx, args...)
+}
+
+func (db *queryWrapper) exec(ctx context.Context, prepared bool, query string, args []any) error {
+	if !prepared || db.prepare == nil {
+		_, err := db.db.ExecContext(ctx, query, args...)
+		return err
+	}
+
+	stmt, release, err := db.prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = stmt.ExecContext(ctx, args...)
 	return err
 }
 
+func (db *queryWrapper) translateError(err error) error {
+	if err == nil || db.options.errorRegistry == nil {
+		return err
+	}
+
+	return db.options.errorRegistry.Translate(err)
+}
+
+// translatingRows translates errors that surface while rows are read (e.g., a
+// constraint violated by a function evaluated per row) with the error registry of the
+// database that returned them.
+type translatingRows struct {
+	*sql.Rows
+	registry *ErrorRegistry
+}
+
+func (r *translatingRows) Close() error {
+	return r.registry.Translate(r.Rows.Close())
+}
+
+func (r *translatingRows) Err() error {
+	return r.registry.Translate(r.Rows.Err())
+}
+
 func (db *queryWrapper) lock() {
 	if db.mu == nil {
 		return
@@ -77,7 +166,7 @@ func (db *queryWrapper) unlock() {
 	db.mu.Unlock()
 }
 
-func logQuery(logger nacelle.Logger, duration time.Duration, err error, query string, args []any) {
+func logQuery(logger nacelle.Logger, duration time.Duration, err error, query string, args []any, logDebugQuery bool) {
 	fields := nacelle.LogFields{
 		"query":    query,
 		"args":     args,
@@ -85,6 +174,10 @@ func logQuery(logger nacelle.Logger, duration time.Duration, err error, query st
 		"duration": duration,
 	}
 
+	if logDebugQuery {
+		fields["debug_query"] = debugString(query, args)
+	}
+
 	logger.DebugWithFields(fields, "sql query executed")
 }
 
@@ -10,7 +10,7 @@ import (
 
 const MaxPingAttempts = 15
 
-func Dial(url string, logger nacelle.Logger) (DB, error) {
+func Dial(url string, logger nacelle.Logger, configs ...DialConfigFunc) (DB, error) {
 	db, err := sql.Open("postgres", url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database (%s)", err)
@@ -30,5 +30,5 @@ func Dial(url string, logger nacelle.Logger) (DB, error) {
 		<-time.After(time.Second * 2)
 	}
 
-	return newLoggingDB(db, logger), nil
+	return newLoggingDB(db, logger, configs...), nil
 }
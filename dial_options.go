@@ -0,0 +1,47 @@
+package pgutil
+
+type (
+	dialOptions struct {
+		logDebugQueries            bool
+		preparedStatementCacheSize int
+		errorRegistry              *ErrorRegistry
+	}
+
+	// DialConfigFunc is a function used to configure a database connection.
+	DialConfigFunc func(*dialOptions)
+)
+
+func getDialOptions(configs []DialConfigFunc) *dialOptions {
+	options := &dialOptions{}
+	for _, f := range configs {
+		f(options)
+	}
+
+	return options
+}
+
+// WithDialDebugQueryLogging includes a rendering of each query with its arguments
+// inlined as literals in query logs. See Q.DebugString for caveats.
+func WithDialDebugQueryLogging(enabled bool) DialConfigFunc {
+	return func(o *dialOptions) {
+		o.logDebugQueries = enabled
+	}
+}
+
+// WithDialPreparedStatementCache enables a least-recently-used cache of prepared
+// statements holding up to the given number of entries. Only queries marked with
+// Q.Prepared are prepared and cached.
+func WithDialPreparedStatementCache(capacity int) DialConfigFunc {
+	return func(o *dialOptions) {
+		o.preparedStatementCacheSize = capacity
+	}
+}
+
+// WithDialErrorTranslation passes every error returned from a query through the
+// given registry (see ErrorRegistry.Translate), so that callers receive domain
+// errors rather than raw driver errors.
+func WithDialErrorTranslation(registry *ErrorRegistry) DialConfigFunc {
+	return func(o *dialOptions) {
+		o.errorRegistry = registry
+	}
+}
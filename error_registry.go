@@ -0,0 +1,110 @@
+package pgutil
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrorRegistry maps classified database errors to domain errors (e.g., a unique
+// violation of the users_email_key constraint to ErrEmailTaken).
+type ErrorRegistry struct {
+	mu          sync.RWMutex
+	constraints map[string]error
+	tables      map[tableErrorKey]error
+}
+
+type tableErrorKey struct {
+	table string
+	kind  error
+}
+
+// DefaultErrorRegistry is consulted by HandleError. Registered domain errors are
+// returned by HandleError unwrapped.
+var DefaultErrorRegistry = NewErrorRegistry()
+
+func NewErrorRegistry() *ErrorRegistry {
+	return &ErrorRegistry{
+		constraints: map[string]error{},
+		tables:      map[tableErrorKey]error{},
+	}
+}
+
+// RegisterConstraint maps any violation of the given constraint to err.
+func (r *ErrorRegistry) RegisterConstraint(constraint string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.constraints[constraint] = err
+}
+
+// RegisterTable maps errors of the given kind (e.g., ErrUniqueViolation) raised on
+// the given table to err. A nil kind matches any classified error on the table.
+// Constraint mappings take precedence over table mappings.
+func (r *ErrorRegistry) RegisterTable(table string, kind, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tables[tableErrorKey{table: table, kind: kind}] = err
+}
+
+// Lookup returns the domain error registered for the given classified error.
+func (r *ErrorRegistry) Lookup(classified *PostgresError) (error, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if classified.Constraint != "" {
+		if err, ok := r.constraints[classified.Constraint]; ok {
+			return err, true
+		}
+	}
+
+	if classified.Table != "" {
+		if err, ok := r.tables[tableErrorKey{table: classified.Table, kind: classified.Kind}]; ok {
+			return err, true
+		}
+		if err, ok := r.tables[tableErrorKey{table: classified.Table}]; ok {
+			return err, true
+		}
+	}
+
+	return nil, false
+}
+
+// Translate converts the given error into a *MappedError if a domain error has been
+// registered for it, or into a *PostgresError if it can be classified. Other errors
+// are returned unchanged.
+func (r *ErrorRegistry) Translate(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var mapped *MappedError
+	if errors.As(err, &mapped) {
+		return err
+	}
+
+	classified, ok := ClassifyError(err)
+	if !ok {
+		return err
+	}
+
+	if domainErr, ok := r.Lookup(classified); ok {
+		return &MappedError{Err: domainErr, Cause: classified}
+	}
+
+	return classified
+}
+
+// MappedError is a domain error registered in an ErrorRegistry along with the
+// classified database error that triggered it. Both match via errors.Is and errors.As.
+type MappedError struct {
+	Err   error
+	Cause *PostgresError
+}
+
+func (e *MappedError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Err, e.Cause)
+}
+
+func (e *MappedError) Unwrap() []error {
+	return []error{e.Err, e.Cause}
+}
@@ -0,0 +1,135 @@
+package pgutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-nacelle/log/v2"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorRegistry(t *testing.T) {
+	var (
+		errEmailTaken  = errors.New("email taken")
+		errInvalidUser = errors.New("invalid user")
+	)
+
+	registry := NewErrorRegistry()
+	registry.RegisterConstraint("users_email_key", errEmailTaken)
+	registry.RegisterTable("users", nil, errInvalidUser)
+
+	t.Run("constraint", func(t *testing.T) {
+		err := registry.Translate(&pq.Error{Code: "23505", Table: "users", Constraint: "users_email_key", Message: "duplicate key value"})
+		assert.ErrorIs(t, err, errEmailTaken)
+		assert.ErrorIs(t, err, ErrUniqueViolation)
+		assert.ErrorIs(t, err, ErrAlreadyExists)
+		assert.EqualError(t, err, "email taken: pq: duplicate key value")
+
+		var pgErr *PostgresError
+		require.ErrorAs(t, err, &pgErr)
+		assert.Equal(t, "users_email_key", pgErr.Constraint)
+
+		// Already-translated errors are unchanged
+		assert.Equal(t, err, registry.Translate(err))
+	})
+
+	t.Run("table", func(t *testing.T) {
+		err := registry.Translate(&pq.Error{Code: "23514", Table: "users", Constraint: "users_age_check"})
+		assert.ErrorIs(t, err, errInvalidUser)
+		assert.ErrorIs(t, err, ErrCheckViolation)
+	})
+
+	t.Run("table and kind", func(t *testing.T) {
+		registry := NewErrorRegistry()
+		registry.RegisterTable("posts", ErrForeignKeyViolation, errInvalidUser)
+
+		err := registry.Translate(&pq.Error{Code: "23503", Table: "posts"})
+		assert.ErrorIs(t, err, errInvalidUser)
+
+		err = registry.Translate(&pq.Error{Code: "23505", Table: "posts"})
+		assert.NotErrorIs(t, err, errInvalidUser)
+		assert.ErrorIs(t, err, ErrUniqueViolation)
+	})
+
+	t.Run("unmapped", func(t *testing.T) {
+		err := registry.Translate(&pq.Error{Code: "23505", Table: "posts"})
+		var pgErr *PostgresError
+		assert.ErrorAs(t, err, &pgErr)
+
+		plain := errors.New("oops")
+		assert.Equal(t, plain, registry.Translate(plain))
+		assert.Nil(t, registry.Translate(nil))
+	})
+}
+
+func TestDialErrorTranslation(t *testing.T) {
+	errEmailTaken := errors.New("email taken")
+	registry := NewErrorRegistry()
+	registry.RegisterConstraint("users_email_key", errEmailTaken)
+
+	db := NewTestDBWithLogger(t, log.NewNilLogger(), WithDialErrorTranslation(registry))
+	ctx := context.Background()
+
+	require.NoError(t, db.Exec(ctx, RawQuery(`CREATE TABLE users (email text CONSTRAINT users_email_key UNIQUE)`)))
+	require.NoError(t, db.Exec(ctx, RawQuery(`INSERT INTO users (email) VALUES ('test@example.com')`)))
+
+	err := db.Exec(ctx, RawQuery(`INSERT INTO users (email) VALUES ('test@example.com')`))
+	assert.ErrorIs(t, err, errEmailTaken)
+
+	err = db.WithTransaction(ctx, func(tx DB) error {
+		return tx.Exec(ctx, RawQuery(`INSERT INTO users (email) VALUES ('test@example.com')`))
+	})
+	assert.ErrorIs(t, err, errEmailTaken)
+}
+
+func TestDialErrorTranslationDeferred(t *testing.T) {
+	errEmailTaken := errors.New("email taken")
+	registry := NewErrorRegistry()
+	registry.RegisterConstraint("users_email_key", errEmailTaken)
+
+	db := NewTestDBWithLogger(t, log.NewNilLogger(), WithDialErrorTranslation(registry))
+	ctx := context.Background()
+
+	require.NoError(t, db.Exec(ctx, RawQuery(`
+		CREATE TABLE users (email text CONSTRAINT users_email_key UNIQUE DEFERRABLE INITIALLY DEFERRED);
+		INSERT INTO users (email) VALUES ('test@example.com');
+	`)))
+
+	t.Run("commit", func(t *testing.T) {
+		err := db.WithTransaction(ctx, func(tx DB) error {
+			// The violation is reported only on commit
+			return tx.Exec(ctx, RawQuery(`INSERT INTO users (email) VALUES ('test@example.com')`))
+		})
+		assert.ErrorIs(t, err, errEmailTaken)
+		assert.ErrorIs(t, err, ErrUniqueViolation)
+	})
+
+	t.Run("scan", func(t *testing.T) {
+		require.NoError(t, db.Exec(ctx, RawQuery(`
+			CREATE FUNCTION raise_email_taken(i integer) RETURNS integer AS $$
+			BEGIN
+				IF i > 2 THEN
+					RAISE EXCEPTION 'duplicate email' USING ERRCODE = 'unique_violation', CONSTRAINT = 'users_email_key';
+				END IF;
+				RETURN i;
+			END;
+			$$ LANGUAGE plpgsql;
+		`)))
+
+		_, err := ScanInts(db.Query(ctx, RawQuery(`SELECT raise_email_taken(i) FROM generate_series(1, 3) i`)))
+		assert.ErrorIs(t, err, errEmailTaken)
+
+		// Rows read without a scanner are translated as well
+		rows, err := db.Query(ctx, RawQuery(`SELECT raise_email_taken(i) FROM generate_series(1, 3) i`))
+		require.NoError(t, err)
+		defer rows.Close()
+
+		for rows.Next() {
+			// drain
+		}
+		assert.ErrorIs(t, rows.Err(), errEmailTaken)
+	})
+}
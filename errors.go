@@ -2,8 +2,14 @@ package pgutil
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"strings"
 
+	"github.com/jackc/pgconn"
 	"github.com/lib/pq"
 )
 
@@ -11,22 +17,157 @@ var (
 	ErrDoesNotExist  = fmt.Errorf("record does not exist")
 	ErrAlreadyExists = fmt.Errorf("record already exists")
 
+	ErrForeignKeyViolation  = fmt.Errorf("foreign key violation: %w", ErrDoesNotExist)
+	ErrUniqueViolation      = fmt.Errorf("unique violation: %w", ErrAlreadyExists)
+	ErrCheckViolation       = fmt.Errorf("check violation")
+	ErrNotNullViolation     = fmt.Errorf("not-null violation")
+	ErrExclusionViolation   = fmt.Errorf("exclusion violation")
+	ErrSerializationFailure = fmt.Errorf("serialization failure")
+	ErrDeadlockDetected     = fmt.Errorf("deadlock detected")
+	ErrLockNotAvailable     = fmt.Errorf("lock not available")
+	ErrQueryCanceled        = fmt.Errorf("query canceled")
+	ErrConnectionLost       = fmt.Errorf("connection lost")
+
 	postgresErrorMap = map[string]error{
-		"foreign_key_violation": ErrDoesNotExist,
-		"unique_violation":      ErrAlreadyExists,
+		"23503": ErrForeignKeyViolation,
+		"23505": ErrUniqueViolation,
+		"23514": ErrCheckViolation,
+		"23502": ErrNotNullViolation,
+		"23P01": ErrExclusionViolation,
+		"40001": ErrSerializationFailure,
+		"40P01": ErrDeadlockDetected,
+		"55P03": ErrLockNotAvailable,
+		"57014": ErrQueryCanceled,
+		"57P01": ErrConnectionLost, // admin_shutdown
+		"57P02": ErrConnectionLost, // crash_shutdown
+	}
+
+	// legacyErrorMap lists the errors for which HandleError returns a bare sentinel.
+	legacyErrorMap = map[string]error{
+		"23503": ErrDoesNotExist,
+		"23505": ErrAlreadyExists,
 	}
 )
 
+// PostgresError is a classified error returned from the database. It matches its
+// kind (e.g., ErrUniqueViolation) as well as the underlying driver error via errors.Is
+// and errors.As.
+type PostgresError struct {
+	Kind       error
+	Code       string
+	Message    string
+	Detail     string
+	Schema     string
+	Table      string
+	Column     string
+	Constraint string
+	Err        error
+}
+
+func (e *PostgresError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PostgresError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// ClassifyError returns a *PostgresError describing the given error if it (or an
+// error it wraps) is a lib/pq or pgconn error with a known SQLSTATE, or signals a
+// lost connection.
+func ClassifyError(err error) (*PostgresError, bool) {
+	if err == nil {
+		return nil, false
+	}
+
+	var classified *PostgresError
+	if errors.As(err, &classified) {
+		return classified, true
+	}
+
+	if pgErr, ok := extractPostgresError(err); ok {
+		kind, ok := postgresErrorMap[pgErr.Code]
+		if !ok && strings.HasPrefix(pgErr.Code, "08") {
+			// Class 08 - Connection Exception
+			kind, ok = ErrConnectionLost, true
+		}
+		if !ok {
+			return nil, false
+		}
+
+		pgErr.Kind = kind
+		return pgErr, true
+	}
+
+	if isConnectionLost(err) {
+		return &PostgresError{Kind: ErrConnectionLost, Err: err}, true
+	}
+
+	return nil, false
+}
+
+func extractPostgresError(err error) (*PostgresError, bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return &PostgresError{
+			Code:       string(pqErr.Code),
+			Message:    pqErr.Message,
+			Detail:     pqErr.Detail,
+			Schema:     pqErr.Schema,
+			Table:      pqErr.Table,
+			Column:     pqErr.Column,
+			Constraint: pqErr.Constraint,
+			Err:        err,
+		}, true
+	}
+
+	var pgconnErr *pgconn.PgError
+	if errors.As(err, &pgconnErr) {
+		return &PostgresError{
+			Code:       pgconnErr.Code,
+			Message:    pgconnErr.Message,
+			Detail:     pgconnErr.Detail,
+			Schema:     pgconnErr.SchemaName,
+			Table:      pgconnErr.TableName,
+			Column:     pgconnErr.ColumnName,
+			Constraint: pgconnErr.ConstraintName,
+			Err:        err,
+		}, true
+	}
+
+	return nil, false
+}
+
+func isConnectionLost(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// HandleError returns ErrDoesNotExist for missing rows and foreign key violations,
+// ErrAlreadyExists for unique violations, and the domain error registered in the
+// DefaultErrorRegistry for mapped errors. These are returned unwrapped so they can be
+// compared directly. Other errors are wrapped with the given description; classified
+// errors are wrapped as a *PostgresError.
 func HandleError(err error, description string) error {
 	if err == sql.ErrNoRows {
 		return ErrDoesNotExist
 	}
 
-	if postgresErr, ok := err.(*pq.Error); ok {
-		if err, ok := postgresErrorMap[postgresErr.Code.Name()]; ok {
-			return err
+	if classified, ok := ClassifyError(err); ok {
+		if domainErr, ok := DefaultErrorRegistry.Lookup(classified); ok {
+			return domainErr
+		}
+
+		if sentinel, ok := legacyErrorMap[classified.Code]; ok {
+			return sentinel
 		}
+
+		return fmt.Errorf("%s (%w)", description, classified)
 	}
 
-	return fmt.Errorf("%s (%s)", description, err.Error())
+	return fmt.Errorf("%s (%w)", description, err)
 }
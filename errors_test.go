@@ -0,0 +1,158 @@
+package pgutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleError(t *testing.T) {
+	db := NewTestDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, db.Exec(ctx, RawQuery(`
+		CREATE TABLE users (
+			id    SERIAL PRIMARY KEY,
+			email text NOT NULL CONSTRAINT users_email_key UNIQUE,
+			age   integer CONSTRAINT users_age_check CHECK (age >= 0)
+		);
+		CREATE TABLE posts (
+			id      SERIAL PRIMARY KEY,
+			user_id integer NOT NULL CONSTRAINT posts_user_id_fkey REFERENCES users(id)
+		);
+	`)))
+	require.NoError(t, db.Exec(ctx, RawQuery(`INSERT INTO users (email) VALUES ('test@example.com')`)))
+
+	t.Run("unique", func(t *testing.T) {
+		err := HandleError(db.Exec(ctx, RawQuery(`INSERT INTO users (email) VALUES ('test@example.com')`)), "failed to insert")
+		assert.Equal(t, ErrAlreadyExists, err)
+	})
+
+	t.Run("foreign key", func(t *testing.T) {
+		err := HandleError(db.Exec(ctx, RawQuery(`INSERT INTO posts (user_id) VALUES (42)`)), "failed to insert")
+		assert.Equal(t, ErrDoesNotExist, err)
+	})
+
+	for _, testCase := range []struct {
+		name       string
+		query      string
+		kind       error
+		code       string
+		table      string
+		column     string
+		constraint string
+	}{
+		{"check", `INSERT INTO users (email, age) VALUES ('other@example.com', -1)`, ErrCheckViolation, "23514", "users", "", "users_age_check"},
+		{"not null", `INSERT INTO users (email) VALUES (NULL)`, ErrNotNullViolation, "23502", "users", "email", ""},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := HandleError(db.Exec(ctx, RawQuery(testCase.query)), "failed to insert")
+			require.ErrorIs(t, err, testCase.kind)
+
+			var pgErr *PostgresError
+			require.ErrorAs(t, err, &pgErr)
+			assert.Equal(t, testCase.code, pgErr.Code)
+			assert.Equal(t, testCase.table, pgErr.Table)
+			assert.Equal(t, testCase.column, pgErr.Column)
+			assert.Equal(t, testCase.constraint, pgErr.Constraint)
+
+			var pqErr *pq.Error
+			assert.ErrorAs(t, err, &pqErr)
+		})
+	}
+
+	t.Run("lock not available", func(t *testing.T) {
+		require.NoError(t, db.WithTransaction(ctx, func(tx DB) error {
+			require.NoError(t, tx.Exec(ctx, RawQuery(`LOCK TABLE users IN ACCESS EXCLUSIVE MODE`)))
+
+			return db.WithTransaction(ctx, func(tx DB) error {
+				err := tx.Exec(ctx, RawQuery(`LOCK TABLE users IN ACCESS EXCLUSIVE MODE NOWAIT`))
+				assert.ErrorIs(t, HandleError(err, "failed to lock"), ErrLockNotAvailable)
+				return nil
+			})
+		}))
+	})
+}
+
+func TestClassifyError(t *testing.T) {
+	t.Run("legacy sentinels", func(t *testing.T) {
+		assert.Equal(t, ErrAlreadyExists, HandleError(&pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"}, "failed"))
+		assert.Equal(t, ErrDoesNotExist, HandleError(&pq.Error{Code: "23503"}, "failed"))
+		assert.Equal(t, ErrDoesNotExist, HandleError(sql.ErrNoRows, "failed"))
+	})
+
+	t.Run("pgconn", func(t *testing.T) {
+		classified, ok := ClassifyError(fmt.Errorf("wrapped: %w", &pgconn.PgError{
+			Code:           "40P01",
+			Message:        "deadlock detected",
+			TableName:      "users",
+			ConstraintName: "users_pkey",
+		}))
+		require.True(t, ok)
+		assert.Equal(t, ErrDeadlockDetected, classified.Kind)
+		assert.Equal(t, "users", classified.Table)
+		assert.Equal(t, "users_pkey", classified.Constraint)
+		assert.ErrorIs(t, classified, ErrDeadlockDetected)
+	})
+
+	t.Run("sqlstates", func(t *testing.T) {
+		for code, kind := range map[string]error{
+			"23P01": ErrExclusionViolation,
+			"40001": ErrSerializationFailure,
+			"55P03": ErrLockNotAvailable,
+			"57014": ErrQueryCanceled,
+			"08006": ErrConnectionLost,
+			"57P01": ErrConnectionLost,
+		} {
+			classified, ok := ClassifyError(&pq.Error{Code: pq.ErrorCode(code)})
+			require.True(t, ok, code)
+			assert.ErrorIs(t, classified, kind, code)
+		}
+	})
+
+	t.Run("connection lost", func(t *testing.T) {
+		classified, ok := ClassifyError(driver.ErrBadConn)
+		require.True(t, ok)
+		assert.ErrorIs(t, classified, ErrConnectionLost)
+		assert.ErrorIs(t, classified, driver.ErrBadConn)
+	})
+
+	t.Run("unclassified", func(t *testing.T) {
+		_, ok := ClassifyError(&pq.Error{Code: "42601"})
+		assert.False(t, ok)
+
+		_, ok = ClassifyError(errors.New("oops"))
+		assert.False(t, ok)
+
+		err := HandleError(&pq.Error{Code: "42601", Message: "syntax error"}, "failed")
+		assert.EqualError(t, err, "failed (pq: syntax error)")
+
+		var pqErr *pq.Error
+		assert.ErrorAs(t, err, &pqErr)
+	})
+}
+
+func TestHandleErrorRegistered(t *testing.T) {
+	errEmailTaken := errors.New("email taken")
+	DefaultErrorRegistry.RegisterConstraint("handle_error_test_email_key", errEmailTaken)
+	t.Cleanup(func() {
+		DefaultErrorRegistry.mu.Lock()
+		defer DefaultErrorRegistry.mu.Unlock()
+		delete(DefaultErrorRegistry.constraints, "handle_error_test_email_key")
+	})
+
+	assert.Equal(t, errEmailTaken, HandleError(&pq.Error{Code: "23505", Constraint: "handle_error_test_email_key"}, "failed"))
+	assert.Equal(t, ErrAlreadyExists, HandleError(&pq.Error{Code: "23505", Constraint: "other_key"}, "failed"))
+
+	err := HandleError(&pq.Error{Code: "23514", Message: "check violation"}, "failed")
+	assert.EqualError(t, err, "failed (pq: check violation)")
+	assert.ErrorIs(t, err, ErrCheckViolation)
+}
@@ -26,12 +26,18 @@ func (i *Initializer) Init(config nacelle.Config) error {
 		return err
 	}
 
+	// Debug query logging is an extension of query logging, so it implies it
 	logger := i.Logger
-	if !dbConfig.LogSQLQueries {
+	if !dbConfig.LogSQLQueries && !dbConfig.LogSQLDebugQueries {
 		logger = nacelle.NewNilLogger()
 	}
 
-	db, err := Dial(dbConfig.DatabaseURL, logger)
+	db, err := Dial(
+		dbConfig.DatabaseURL,
+		logger,
+		WithDialDebugQueryLogging(dbConfig.LogSQLDebugQueries),
+		WithDialPreparedStatementCache(dbConfig.PreparedStatementCacheSize),
+	)
 	if err != nil {
 		return err
 	}
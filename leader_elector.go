@@ -0,0 +1,119 @@
+package pgutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-nacelle/nacelle/v2"
+)
+
+// LeaderElector ensures that at most one instance of a process is active at a time
+// by holding a session-level advisory lock on a pinned connection. Instances that
+// are not elected periodically re-campaign for the lock, as does the leader after
+// losing its connection.
+//
+// LeaderElector implements nacelle's Runner and Stopper interfaces and can be
+// registered directly as a process.
+type LeaderElector struct {
+	Logger nacelle.Logger `service:"logger"`
+
+	locker        *SessionLocker
+	key           int32
+	retryInterval time.Duration
+	onElected     func(ctx context.Context)
+	onDemoted     func()
+	leader        atomic.Bool
+	halt          chan struct{}
+	haltOnce      sync.Once
+}
+
+func NewLeaderElector(db DB, namespace, key int32, configs ...LeaderElectorConfigFunc) (*LeaderElector, error) {
+	options := getLeaderElectorOptions(configs)
+
+	locker, err := NewSessionLocker(db, namespace, WithSessionLockerPingInterval(options.pingInterval))
+	if err != nil {
+		return nil, err
+	}
+
+	elector := &LeaderElector{
+		locker:        locker,
+		key:           key,
+		retryInterval: options.retryInterval,
+		onElected:     options.onElected,
+		onDemoted:     options.onDemoted,
+		halt:          make(chan struct{}),
+	}
+
+	return elector, nil
+}
+
+// IsLeader returns true if this instance currently holds the leader lock.
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run campaigns for leadership until the given context is canceled or Stop is called.
+func (e *LeaderElector) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-e.halt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		if _, err := e.locker.TryWithLock(ctx, e.key, e.lead); err != nil && ctx.Err() == nil {
+			if errors.Is(err, ErrLockLost) {
+				e.logger().Warning("Lost leadership (%s)", err)
+			} else {
+				e.logger().Error("Failed to campaign for leadership (%s)", err)
+			}
+		}
+
+		select {
+		case <-time.After(e.retryInterval):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Stop relinquishes leadership (if held) and causes Run to return.
+func (e *LeaderElector) Stop(ctx context.Context) error {
+	e.haltOnce.Do(func() { close(e.halt) })
+	return nil
+}
+
+func (e *LeaderElector) lead(ctx context.Context) error {
+	e.leader.Store(true)
+	e.logger().Info("Elected leader")
+	if e.onElected != nil {
+		e.onElected(ctx)
+	}
+
+	// Hold the lock until canceled or the connection is lost
+	<-ctx.Done()
+
+	e.leader.Store(false)
+	e.logger().Info("Demoted from leader")
+	if e.onDemoted != nil {
+		e.onDemoted()
+	}
+
+	return nil
+}
+
+func (e *LeaderElector) logger() nacelle.Logger {
+	if e.Logger == nil {
+		return nacelle.NewNilLogger()
+	}
+
+	return e.Logger
+}
@@ -0,0 +1,62 @@
+package pgutil
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	leaderElectorOptions struct {
+		retryInterval time.Duration
+		pingInterval  time.Duration
+		onElected     func(ctx context.Context)
+		onDemoted     func()
+	}
+
+	// LeaderElectorConfigFunc is a function used to configure a leader elector.
+	LeaderElectorConfigFunc func(*leaderElectorOptions)
+)
+
+func getLeaderElectorOptions(configs []LeaderElectorConfigFunc) *leaderElectorOptions {
+	options := &leaderElectorOptions{
+		retryInterval: 5 * time.Second,
+		pingInterval:  5 * time.Second,
+	}
+
+	for _, f := range configs {
+		f(options)
+	}
+
+	return options
+}
+
+// WithLeaderElectorRetryInterval sets how often a non-leader campaigns for leadership.
+func WithLeaderElectorRetryInterval(interval time.Duration) LeaderElectorConfigFunc {
+	return func(o *leaderElectorOptions) {
+		o.retryInterval = interval
+	}
+}
+
+// WithLeaderElectorPingInterval sets how often the leader's connection is checked
+// for liveness. See WithSessionLockerPingInterval.
+func WithLeaderElectorPingInterval(interval time.Duration) LeaderElectorConfigFunc {
+	return func(o *leaderElectorOptions) {
+		o.pingInterval = interval
+	}
+}
+
+// WithLeaderElectorOnElected registers a callback invoked when this instance becomes
+// the leader. The given context is canceled on demotion. The callback should not block.
+func WithLeaderElectorOnElected(f func(ctx context.Context)) LeaderElectorConfigFunc {
+	return func(o *leaderElectorOptions) {
+		o.onElected = f
+	}
+}
+
+// WithLeaderElectorOnDemoted registers a callback invoked when this instance stops
+// being the leader.
+func WithLeaderElectorOnDemoted(f func()) LeaderElectorConfigFunc {
+	return func(o *leaderElectorOptions) {
+		o.onDemoted = f
+	}
+}
@@ -0,0 +1,69 @@
+package pgutil
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderElector(t *testing.T) {
+	var (
+		db  = NewTestDB(t)
+		ctx = context.Background()
+	)
+
+	var elected, demoted atomic.Int32
+	newElector := func() *LeaderElector {
+		elector, err := NewLeaderElector(db, StringKey("test"), StringKey("leader"),
+			WithLeaderElectorRetryInterval(10*time.Millisecond),
+			WithLeaderElectorPingInterval(10*time.Millisecond),
+			WithLeaderElectorOnElected(func(ctx context.Context) { elected.Add(1) }),
+			WithLeaderElectorOnDemoted(func() { demoted.Add(1) }),
+		)
+		require.NoError(t, err)
+		return elector
+	}
+
+	var wg sync.WaitGroup
+	run := func(elector *LeaderElector) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, elector.Run(ctx))
+		}()
+	}
+
+	first := newElector()
+	run(first)
+	require.Eventually(t, first.IsLeader, 5*time.Second, 10*time.Millisecond)
+
+	second := newElector()
+	run(second)
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, second.IsLeader())
+
+	// Stepping down elects the other instance
+	require.NoError(t, first.Stop(ctx))
+	require.Eventually(t, second.IsLeader, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, first.IsLeader())
+
+	// Re-campaign after the leader's connection is lost
+	require.NoError(t, db.Exec(ctx, Query(`
+		SELECT pg_terminate_backend(pid)
+		FROM pg_locks
+		WHERE locktype = 'advisory' AND objid = {:key}::oid
+	`, Args{"key": StringKey("leader")})))
+	require.Eventually(t, func() bool { return demoted.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, second.IsLeader, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, second.Stop(ctx))
+	wg.Wait()
+
+	assert.Equal(t, int32(3), elected.Load())
+	assert.Equal(t, int32(3), demoted.Load())
+}
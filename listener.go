@@ -0,0 +1,57 @@
+package pgutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// listenForWakeups listens on the given channel over a dedicated connection to the
+// given database and signals the returned channel whenever a notification with the
+// given payload arrives. The returned function closes the listener and waits for
+// the goroutine relaying its notifications to exit.
+func listenForWakeups(ctx context.Context, databaseURL, channel, payload string) (<-chan struct{}, func(), error) {
+	listener := pq.NewListener(databaseURL, 100*time.Millisecond, time.Minute, nil)
+	if err := listener.Listen(channel); err != nil {
+		_ = listener.Close()
+		return nil, nil, err
+	}
+
+	wakeup := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for {
+			select {
+			case notification, ok := <-listener.Notify:
+				if !ok {
+					// Closed along with the listener
+					return
+				}
+
+				// A nil notification signals a re-established connection, during which
+				// notifications may have been missed
+				if notification != nil && notification.Extra != payload {
+					continue
+				}
+
+				select {
+				case wakeup <- struct{}{}:
+				default:
+				}
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	closeListener := func() {
+		_ = listener.Close()
+		<-done
+	}
+
+	return wakeup, closeListener, nil
+}
@@ -0,0 +1,49 @@
+package pgutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-nacelle/log/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenForWakeups(t *testing.T) {
+	ctx := context.Background()
+
+	// Notifications are scoped to a database, so notify on the listener's database
+	db, err := Dial(BuildDatabaseURL(), log.NewNilLogger())
+	require.NoError(t, err)
+
+	wakeup, closeListener, err := listenForWakeups(ctx, BuildDatabaseURL(), "pgutil_test", "payload")
+	require.NoError(t, err)
+
+	require.NoError(t, db.Exec(ctx, RawQuery("SELECT pg_notify('pgutil_test', 'other')")))
+	require.NoError(t, db.Exec(ctx, RawQuery("SELECT pg_notify('pgutil_test', 'payload')")))
+
+	select {
+	case <-wakeup:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for wakeup")
+	}
+
+	// Closing the listener while the context is live stops the relaying goroutine
+	closed := make(chan struct{})
+	go func() {
+		closeListener()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for listener goroutine to exit")
+	}
+
+	select {
+	case <-wakeup:
+		t.Fatal("unexpected wakeup after close")
+	default:
+	}
+}
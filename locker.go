@@ -3,7 +3,9 @@ package pgutil
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
+	"slices"
 
 	"github.com/segmentio/fasthash/fnv1"
 )
@@ -19,6 +21,13 @@ func StringKey(key string) int32 {
 	return int32(fnv1.HashString32(key) % math.MaxInt32)
 }
 
+// StringKey64 derives a 64-bit lock key from the given string for use with the
+// single-key lock methods (e.g., WithLock64). With the full 64-bit keyspace, the
+// chance of unrelated keys colliding is negligible.
+func StringKey64(key string) int64 {
+	return int64(fnv1.HashString64(key))
+}
+
 func NewTransactionalLocker(db DB, namespace int32) (*TransactionalLocker, error) {
 	if db.IsInTransaction() {
 		return nil, ErrInTransaction
@@ -33,11 +42,49 @@ func NewTransactionalLocker(db DB, namespace int32) (*TransactionalLocker, error
 }
 
 func (l *TransactionalLocker) WithLock(ctx context.Context, key int32, f func(tx DB) error) error {
+	return l.withLock(ctx, "pg_advisory_xact_lock", key, f)
+}
+
+func (l *TransactionalLocker) TryWithLock(ctx context.Context, key int32, f func(tx DB) error) (acquired bool, _ error) {
+	return l.tryWithLock(ctx, "pg_try_advisory_xact_lock", key, f)
+}
+
+// WithSharedLock is like WithLock, but the lock may be held concurrently by other
+// shared lock holders. It excludes (and is excluded by) exclusive lock holders.
+func (l *TransactionalLocker) WithSharedLock(ctx context.Context, key int32, f func(tx DB) error) error {
+	return l.withLock(ctx, "pg_advisory_xact_lock_shared", key, f)
+}
+
+// TryWithSharedLock is like TryWithLock, but acquires a shared lock.
+func (l *TransactionalLocker) TryWithSharedLock(ctx context.Context, key int32, f func(tx DB) error) (acquired bool, _ error) {
+	return l.tryWithLock(ctx, "pg_try_advisory_xact_lock_shared", key, f)
+}
+
+// WithLocks acquires the locks for all of the given keys within a single transaction
+// before invoking f. Keys are locked in ascending order so that concurrent callers
+// with overlapping keys cannot deadlock.
+func (l *TransactionalLocker) WithLocks(ctx context.Context, keys []int32, f func(tx DB) error) error {
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
 	return l.db.WithTransaction(ctx, func(tx DB) error {
-		if err := tx.Exec(ctx, Query("SELECT pg_advisory_xact_lock({:namespace}, {:key})", Args{
-			"namespace": l.namespace,
-			"key":       key,
-		})); err != nil {
+		for _, key := range keys {
+			if err := tx.Exec(ctx, advisoryLockQuery("pg_advisory_xact_lock", l.namespace, key)); err != nil {
+				return err
+			}
+		}
+
+		return f(tx)
+	})
+}
+
+// WithLock64 is like WithLock, but takes a single 64-bit key (see StringKey64). The
+// locker's namespace is not used; single-key locks occupy a keyspace distinct from
+// that of two-key locks.
+func (l *TransactionalLocker) WithLock64(ctx context.Context, key int64, f func(tx DB) error) error {
+	return l.db.WithTransaction(ctx, func(tx DB) error {
+		if err := tx.Exec(ctx, advisoryLockQuery64("pg_advisory_xact_lock", key)); err != nil {
 			return err
 		}
 
@@ -45,12 +92,34 @@ func (l *TransactionalLocker) WithLock(ctx context.Context, key int32, f func(tx
 	})
 }
 
-func (l *TransactionalLocker) TryWithLock(ctx context.Context, key int32, f func(tx DB) error) (acquired bool, _ error) {
+// TryWithLock64 is like TryWithLock, but takes a single 64-bit key. See WithLock64.
+func (l *TransactionalLocker) TryWithLock64(ctx context.Context, key int64, f func(tx DB) error) (acquired bool, _ error) {
+	err := l.db.WithTransaction(ctx, func(tx DB) (err error) {
+		if acquired, _, err = ScanBool(tx.Query(ctx, advisoryLockQuery64("pg_try_advisory_xact_lock", key))); err != nil {
+			return err
+		} else if !acquired {
+			return nil
+		}
+
+		return f(tx)
+	})
+
+	return acquired, err
+}
+
+func (l *TransactionalLocker) withLock(ctx context.Context, lockFunc string, key int32, f func(tx DB) error) error {
+	return l.db.WithTransaction(ctx, func(tx DB) error {
+		if err := tx.Exec(ctx, advisoryLockQuery(lockFunc, l.namespace, key)); err != nil {
+			return err
+		}
+
+		return f(tx)
+	})
+}
+
+func (l *TransactionalLocker) tryWithLock(ctx context.Context, lockFunc string, key int32, f func(tx DB) error) (acquired bool, _ error) {
 	err := l.db.WithTransaction(ctx, func(tx DB) (err error) {
-		if acquired, _, err = ScanBool(tx.Query(ctx, Query("SELECT pg_try_advisory_xact_lock({:namespace}, {:key})", Args{
-			"namespace": l.namespace,
-			"key":       key,
-		}))); err != nil {
+		if acquired, _, err = ScanBool(tx.Query(ctx, advisoryLockQuery(lockFunc, l.namespace, key))); err != nil {
 			return err
 		} else if !acquired {
 			return nil
@@ -61,3 +130,16 @@ func (l *TransactionalLocker) TryWithLock(ctx context.Context, key int32, f func
 
 	return acquired, err
 }
+
+func advisoryLockQuery(lockFunc string, namespace, key int32) Q {
+	return Query(fmt.Sprintf("SELECT %s({:namespace}, {:key})", lockFunc), Args{
+		"namespace": namespace,
+		"key":       key,
+	})
+}
+
+func advisoryLockQuery64(lockFunc string, key int64) Q {
+	return Query(fmt.Sprintf("SELECT %s({:key}::bigint)", lockFunc), Args{
+		"key": key,
+	})
+}
@@ -0,0 +1,144 @@
+package pgutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrLockTimeout = errors.New("timed out waiting for advisory lock")
+
+type LockTimeoutError struct {
+	Namespace int32
+	Key       int32
+	Timeout   time.Duration
+}
+
+func (e *LockTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for advisory lock (%d, %d)", e.Timeout, e.Namespace, e.Key)
+}
+
+func (e *LockTimeoutError) Is(target error) bool {
+	return target == ErrLockTimeout
+}
+
+// WithLockTimeout is like WithLock, but gives up with a *LockTimeoutError (matching
+// ErrLockTimeout) if the lock cannot be acquired within the given duration. The wait
+// is bounded by the server via lock_timeout, which is restored before f is invoked.
+func (l *TransactionalLocker) WithLockTimeout(ctx context.Context, key int32, timeout time.Duration, f func(tx DB) error) error {
+	return l.db.WithTransaction(ctx, func(tx DB) error {
+		previous, _, err := ScanString(tx.Query(ctx, RawQuery("SELECT current_setting('lock_timeout')")))
+		if err != nil {
+			return err
+		}
+
+		if err := setLocalLockTimeout(ctx, tx, formatLockTimeout(timeout)); err != nil {
+			return err
+		}
+
+		if err := tx.Exec(ctx, advisoryLockQuery("pg_advisory_xact_lock", l.namespace, key)); err != nil {
+			if classified, ok := ClassifyError(err); ok && errors.Is(classified, ErrLockNotAvailable) {
+				return &LockTimeoutError{Namespace: l.namespace, Key: key, Timeout: timeout}
+			}
+
+			return err
+		}
+
+		if err := setLocalLockTimeout(ctx, tx, previous); err != nil {
+			return err
+		}
+
+		return f(tx)
+	})
+}
+
+func setLocalLockTimeout(ctx context.Context, tx DB, value string) error {
+	return tx.Exec(ctx, Query("SELECT set_config('lock_timeout', {:value}, true)", Args{"value": value}))
+}
+
+func formatLockTimeout(timeout time.Duration) string {
+	// A lock_timeout of zero disables the timeout entirely
+	ms := timeout.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+
+	return fmt.Sprintf("%dms", ms)
+}
+
+// AdvisoryLockProcess describes a backend that holds or awaits an advisory lock.
+type AdvisoryLockProcess struct {
+	PID     int
+	Mode    string // ExclusiveLock or ShareLock
+	Granted bool
+}
+
+type AdvisoryLockState struct {
+	Holders []AdvisoryLockProcess
+	Waiters []AdvisoryLockProcess
+}
+
+var scanAdvisoryLockProcesses = NewSliceScanner(func(s Scanner) (p AdvisoryLockProcess, _ error) {
+	err := s.Scan(&p.PID, &p.Mode, &p.Granted)
+	return p, err
+})
+
+// InspectAdvisoryLock returns the backends in the current database that hold or are
+// waiting on the advisory lock with the given namespace and key.
+func InspectAdvisoryLock(ctx context.Context, db DB, namespace, key int32) (AdvisoryLockState, error) {
+	// Two-key advisory locks are stored as unsigned object identifiers
+	return inspectAdvisoryLock(ctx, db, uint32(namespace), uint32(key), 2)
+}
+
+// InspectAdvisoryLock64 returns the backends in the current database that hold or are
+// waiting on the single-key advisory lock with the given key (see WithLock64).
+func InspectAdvisoryLock64(ctx context.Context, db DB, key int64) (AdvisoryLockState, error) {
+	// Single-key advisory locks are stored as the high and low halves of the key
+	return inspectAdvisoryLock(ctx, db, uint32(uint64(key)>>32), uint32(key), 1)
+}
+
+func inspectAdvisoryLock(ctx context.Context, db DB, classID, objID uint32, objSubID int) (AdvisoryLockState, error) {
+	processes, err := scanAdvisoryLockProcesses(db.Query(ctx, Query(`
+		SELECT l.pid, l.mode, l.granted
+		FROM pg_catalog.pg_locks l
+		JOIN pg_catalog.pg_database d ON d.oid = l.database
+		WHERE
+			l.locktype = 'advisory' AND
+			d.datname = current_database() AND
+			l.classid = {:class_id}::oid AND
+			l.objid = {:obj_id}::oid AND
+			l.objsubid = {:obj_sub_id}
+		ORDER BY l.pid
+	`, Args{
+		"class_id":   int64(classID),
+		"obj_id":     int64(objID),
+		"obj_sub_id": objSubID,
+	})))
+	if err != nil {
+		return AdvisoryLockState{}, err
+	}
+
+	var state AdvisoryLockState
+	for _, process := range processes {
+		if process.Granted {
+			state.Holders = append(state.Holders, process)
+		} else {
+			state.Waiters = append(state.Waiters, process)
+		}
+	}
+
+	return state, nil
+}
+
+func (l *TransactionalLocker) Inspect(ctx context.Context, key int32) (AdvisoryLockState, error) {
+	return InspectAdvisoryLock(ctx, l.db, l.namespace, key)
+}
+
+func (l *TransactionalLocker) Inspect64(ctx context.Context, key int64) (AdvisoryLockState, error) {
+	return InspectAdvisoryLock64(ctx, l.db, key)
+}
+
+func (l *SessionLocker) Inspect(ctx context.Context, key int32) (AdvisoryLockState, error) {
+	return InspectAdvisoryLock(ctx, l.db, l.namespace, key)
+}
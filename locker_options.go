@@ -0,0 +1,32 @@
+package pgutil
+
+import "time"
+
+type (
+	sessionLockerOptions struct {
+		pingInterval time.Duration
+	}
+
+	// SessionLockerConfigFunc is a function used to configure a session locker.
+	SessionLockerConfigFunc func(*sessionLockerOptions)
+)
+
+func getSessionLockerOptions(configs []SessionLockerConfigFunc) *sessionLockerOptions {
+	options := &sessionLockerOptions{
+		pingInterval: 5 * time.Second,
+	}
+
+	for _, f := range configs {
+		f(options)
+	}
+
+	return options
+}
+
+// WithSessionLockerPingInterval sets how often the connection holding a lock is
+// checked for liveness.
+func WithSessionLockerPingInterval(interval time.Duration) SessionLockerConfigFunc {
+	return func(o *sessionLockerOptions) {
+		o.pingInterval = interval
+	}
+}
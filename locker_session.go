@@ -0,0 +1,157 @@
+package pgutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SessionLocker holds session-level advisory locks (pg_advisory_lock) on a dedicated
+// connection outside of any transaction. This is suitable for long-running work that
+// should not hold a transaction open for its duration.
+type SessionLocker struct {
+	db           DB
+	pinner       connPinner
+	namespace    int32
+	pingInterval time.Duration
+}
+
+type connPinner interface {
+	pinConn(ctx context.Context) (*pinnedConn, error)
+}
+
+var (
+	ErrSessionLockerUnsupported = errors.New("locker database does not support pinned connections")
+	ErrLockLost                 = errors.New("connection holding advisory lock was lost")
+)
+
+func NewSessionLocker(db DB, namespace int32, configs ...SessionLockerConfigFunc) (*SessionLocker, error) {
+	if db.IsInTransaction() {
+		return nil, ErrInTransaction
+	}
+
+	pinner, ok := db.(connPinner)
+	if !ok {
+		return nil, ErrSessionLockerUnsupported
+	}
+
+	options := getSessionLockerOptions(configs)
+
+	locker := &SessionLocker{
+		db:           db,
+		pinner:       pinner,
+		namespace:    namespace,
+		pingInterval: options.pingInterval,
+	}
+
+	return locker, nil
+}
+
+// WithLock blocks until the lock is acquired, then invokes f. The lock is released
+// once f returns, including when ctx is canceled. The context passed to f is canceled
+// if the connection holding the lock is lost, in which case ErrLockLost is returned.
+func (l *SessionLocker) WithLock(ctx context.Context, key int32, f func(ctx context.Context) error) error {
+	_, err := l.withLock(ctx, "pg_advisory_lock", "pg_advisory_unlock", false, key, f)
+	return err
+}
+
+func (l *SessionLocker) TryWithLock(ctx context.Context, key int32, f func(ctx context.Context) error) (acquired bool, _ error) {
+	return l.withLock(ctx, "pg_try_advisory_lock", "pg_advisory_unlock", true, key, f)
+}
+
+// WithSharedLock is like WithLock, but the lock may be held concurrently by other
+// shared lock holders. It excludes (and is excluded by) exclusive lock holders.
+func (l *SessionLocker) WithSharedLock(ctx context.Context, key int32, f func(ctx context.Context) error) error {
+	_, err := l.withLock(ctx, "pg_advisory_lock_shared", "pg_advisory_unlock_shared", false, key, f)
+	return err
+}
+
+// TryWithSharedLock is like TryWithLock, but acquires a shared lock.
+func (l *SessionLocker) TryWithSharedLock(ctx context.Context, key int32, f func(ctx context.Context) error) (acquired bool, _ error) {
+	return l.withLock(ctx, "pg_try_advisory_lock_shared", "pg_advisory_unlock_shared", true, key, f)
+}
+
+// sessionUnlockTimeout bounds the time spent releasing a lock after f returns. If the
+// lock cannot be released in time, the connection is closed instead.
+const sessionUnlockTimeout = 5 * time.Second
+
+func (l *SessionLocker) withLock(ctx context.Context, lockFunc, unlockFunc string, try bool, key int32, f func(ctx context.Context) error) (acquired bool, err error) {
+	conn, err := l.pinner.pinConn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if try {
+		acquired, _, err = ScanBool(conn.Query(ctx, advisoryLockQuery(lockFunc, l.namespace, key)))
+	} else {
+		err = conn.Exec(ctx, advisoryLockQuery(lockFunc, l.namespace, key))
+		acquired = err == nil
+	}
+	if err != nil {
+		// The lock may have been granted concurrently with a cancellation of ctx. Do
+		// not return a connection to the pool that may still hold it.
+		conn.discard()
+		return false, err
+	}
+	if !acquired {
+		return false, conn.release()
+	}
+
+	defer func() {
+		unlockCtx, cancel := context.WithTimeout(context.Background(), sessionUnlockTimeout)
+		defer cancel()
+
+		released, _, unlockErr := ScanBool(conn.Query(unlockCtx, advisoryLockQuery(unlockFunc, l.namespace, key)))
+		if unlockErr != nil || !released {
+			conn.discard()
+
+			if unlockErr != nil && !errors.Is(err, ErrLockLost) {
+				err = errors.Join(err, fmt.Errorf("failed to release advisory lock: %w", unlockErr))
+			}
+
+			return
+		}
+
+		err = errors.Join(err, conn.release())
+	}()
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.monitor(lockCtx, conn, cancel)
+	}()
+
+	err = f(lockCtx)
+	cancel(nil)
+	<-done
+
+	if errors.Is(context.Cause(lockCtx), ErrLockLost) {
+		err = errors.Join(ErrLockLost, err)
+	}
+
+	return true, err
+}
+
+// monitor periodically pings the connection holding the lock and cancels the lock's
+// context if the connection is no longer usable.
+func (l *SessionLocker) monitor(ctx context.Context, conn *pinnedConn, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(l.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		if err := conn.conn.PingContext(ctx); err != nil && ctx.Err() == nil {
+			cancel(ErrLockLost)
+			return
+		}
+	}
+}
@@ -3,6 +3,7 @@ package pgutil
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -80,3 +81,245 @@ func TestLocker(t *testing.T) {
 		assert.True(t, acquired)
 	})
 }
+
+func TestLockerShared(t *testing.T) {
+	var (
+		db  = NewTestDB(t)
+		ctx = context.Background()
+	)
+
+	locker, err := NewTransactionalLocker(db, StringKey("test"))
+	require.NoError(t, err)
+
+	require.NoError(t, locker.WithSharedLock(ctx, 125, func(tx DB) error {
+		// Shared lock can be held concurrently
+		acquired, err := locker.TryWithSharedLock(ctx, 125, func(tx DB) error { return nil })
+		require.NoError(t, err)
+		assert.True(t, acquired)
+
+		// Exclusive lock cannot
+		acquired, err = locker.TryWithLock(ctx, 125, func(tx DB) error { return nil })
+		require.NoError(t, err)
+		assert.False(t, acquired)
+
+		return nil
+	}))
+}
+
+func TestSessionLocker(t *testing.T) {
+	var (
+		db  = NewTestDB(t)
+		ctx = context.Background()
+	)
+
+	locker, err := NewSessionLocker(db, StringKey("test"), WithSessionLockerPingInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	txLocker, err := NewTransactionalLocker(db, StringKey("test"))
+	require.NoError(t, err)
+
+	tryTx := func(key int32) bool {
+		acquired, err := txLocker.TryWithLock(ctx, key, func(tx DB) error { return nil })
+		require.NoError(t, err)
+		return acquired
+	}
+
+	t.Run("exclusive", func(t *testing.T) {
+		require.NoError(t, locker.WithLock(ctx, 125, func(ctx context.Context) error {
+			assert.False(t, tryTx(125))
+			assert.True(t, tryTx(126))
+
+			acquired, err := locker.TryWithSharedLock(ctx, 125, func(ctx context.Context) error { return nil })
+			require.NoError(t, err)
+			assert.False(t, acquired)
+			return nil
+		}))
+
+		// Released
+		assert.True(t, tryTx(125))
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		require.NoError(t, locker.WithSharedLock(ctx, 125, func(ctx context.Context) error {
+			acquired, err := locker.TryWithSharedLock(ctx, 125, func(ctx context.Context) error { return nil })
+			require.NoError(t, err)
+			assert.True(t, acquired)
+
+			acquired, err = locker.TryWithLock(ctx, 125, func(ctx context.Context) error { return nil })
+			require.NoError(t, err)
+			assert.False(t, acquired)
+			return nil
+		}))
+
+		assert.True(t, tryTx(125))
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		err := locker.WithLock(ctx, 125, func(ctx context.Context) error {
+			cancel()
+			return ctx.Err()
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+
+		// Released despite canceled context
+		assert.True(t, tryTx(125))
+	})
+
+	t.Run("connection lost", func(t *testing.T) {
+		err := locker.WithLock(ctx, 125, func(ctx context.Context) error {
+			require.NoError(t, db.Exec(ctx, RawQuery(`
+				SELECT pg_terminate_backend(pid)
+				FROM pg_locks
+				WHERE locktype = 'advisory' AND objid = 125 AND pid <> pg_backend_pid()
+			`)))
+
+			<-ctx.Done()
+			return nil
+		})
+		assert.ErrorIs(t, err, ErrLockLost)
+
+		assert.True(t, tryTx(125))
+	})
+
+	t.Run("in transaction", func(t *testing.T) {
+		require.NoError(t, db.WithTransaction(ctx, func(tx DB) error {
+			_, err := NewSessionLocker(tx, StringKey("test"))
+			assert.ErrorIs(t, err, ErrInTransaction)
+			return nil
+		}))
+	})
+}
+
+func TestLockerTimeout(t *testing.T) {
+	var (
+		db  = NewTestDB(t)
+		ctx = context.Background()
+	)
+
+	locker, err := NewTransactionalLocker(db, StringKey("test"))
+	require.NoError(t, err)
+
+	require.NoError(t, locker.WithLock(ctx, 125, func(tx DB) error {
+		err := locker.WithLockTimeout(ctx, 125, 50*time.Millisecond, func(tx DB) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, ErrLockTimeout)
+
+		var timeoutErr *LockTimeoutError
+		require.ErrorAs(t, err, &timeoutErr)
+		assert.Equal(t, int32(125), timeoutErr.Key)
+
+		// Unheld key is acquired and lock_timeout is restored
+		return locker.WithLockTimeout(ctx, 126, time.Second, func(tx DB) error {
+			value, _, err := ScanString(tx.Query(ctx, RawQuery("SELECT current_setting('lock_timeout')")))
+			require.NoError(t, err)
+			assert.Equal(t, "0", value)
+			return nil
+		})
+	}))
+}
+
+func TestInspectAdvisoryLock(t *testing.T) {
+	var (
+		db  = NewTestDB(t)
+		ctx = context.Background()
+	)
+
+	locker, err := NewTransactionalLocker(db, StringKey("test"))
+	require.NoError(t, err)
+
+	state, err := locker.Inspect(ctx, 125)
+	require.NoError(t, err)
+	assert.Empty(t, state.Holders)
+	assert.Empty(t, state.Waiters)
+
+	errs := make(chan error, 1)
+	require.NoError(t, locker.WithLock(ctx, 125, func(tx DB) error {
+		holderPID, _, err := ScanInt(tx.Query(ctx, RawQuery("SELECT pg_backend_pid()")))
+		require.NoError(t, err)
+
+		go func() {
+			errs <- locker.WithLockTimeout(ctx, 125, 5*time.Second, func(tx DB) error { return nil })
+		}()
+
+		require.Eventually(t, func() bool {
+			state, err = locker.Inspect(ctx, 125)
+			require.NoError(t, err)
+			return len(state.Waiters) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		require.Len(t, state.Holders, 1)
+		assert.Equal(t, holderPID, state.Holders[0].PID)
+		assert.Equal(t, "ExclusiveLock", state.Holders[0].Mode)
+		assert.NotEqual(t, holderPID, state.Waiters[0].PID)
+
+		return nil
+	}))
+
+	// Waiter acquires the lock once released
+	require.NoError(t, <-errs)
+
+	t.Run("64-bit keys", func(t *testing.T) {
+		key := StringKey64("test")
+
+		require.NoError(t, locker.WithLock64(ctx, key, func(tx DB) error {
+			state, err := locker.Inspect64(ctx, key)
+			require.NoError(t, err)
+			require.Len(t, state.Holders, 1)
+
+			// A two-key lock with the same halves is a different lock
+			state, err = InspectAdvisoryLock(ctx, db, int32(uint64(key)>>32), int32(key))
+			require.NoError(t, err)
+			assert.Empty(t, state.Holders)
+
+			return nil
+		}))
+	})
+}
+
+func TestLockerMultipleKeys(t *testing.T) {
+	var (
+		db  = NewTestDB(t)
+		ctx = context.Background()
+	)
+
+	locker, err := NewTransactionalLocker(db, StringKey("test"))
+	require.NoError(t, err)
+
+	require.NoError(t, locker.WithLocks(ctx, []int32{127, 125, 126, 125}, func(tx DB) error {
+		for _, key := range []int32{125, 126, 127} {
+			acquired, err := locker.TryWithLock(ctx, key, func(tx DB) error { return nil })
+			require.NoError(t, err)
+			assert.False(t, acquired)
+		}
+
+		acquired, err := locker.TryWithLock(ctx, 128, func(tx DB) error { return nil })
+		require.NoError(t, err)
+		assert.True(t, acquired)
+		return nil
+	}))
+}
+
+func TestLocker64(t *testing.T) {
+	var (
+		db  = NewTestDB(t)
+		ctx = context.Background()
+	)
+
+	locker, err := NewTransactionalLocker(db, StringKey("test"))
+	require.NoError(t, err)
+
+	key := StringKey64("nacelle/pgutil.test")
+	assert.NotEqual(t, key, StringKey64("nacelle/pgutil.other"))
+
+	require.NoError(t, locker.WithLock64(ctx, key, func(tx DB) error {
+		acquired, err := locker.TryWithLock64(ctx, key, func(tx DB) error { return nil })
+		require.NoError(t, err)
+		assert.False(t, acquired)
+
+		acquired, err = locker.TryWithLock64(ctx, key+1, func(tx DB) error { return nil })
+		require.NoError(t, err)
+		assert.True(t, acquired)
+		return nil
+	}))
+}
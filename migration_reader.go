@@ -1,8 +1,12 @@
 package pgutil
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -12,6 +16,83 @@ type Definition struct {
 	UpQuery       Q
 	DownQuery     Q
 	IndexMetadata *IndexMetadata
+
+	// NoTransactionUp and NoTransactionDown indicate that the migration's up or down
+	// query is not wrapped in a transaction. Each is set by a `-- pgutil:no-transaction`
+	// directive in the corresponding query, and both are set by the migration's
+	// metadata. Note that Postgres still runs multiple statements sent together in an
+	// implicit transaction, so such queries should consist of a single statement.
+	NoTransactionUp   bool
+	NoTransactionDown bool
+
+	Description string
+	Author      string
+
+	// Parents lists the migrations that must be applied before this one. If empty,
+	// the migration's parent is the migration with the next-lowest identifier.
+	Parents []int
+
+	// Squashes lists the migrations replaced by this baseline migration (see
+	// SquashMigrations), including its own identifier. A baseline is applied only to
+	// databases on which none of the migrations it squashes have been applied.
+	Squashes []int
+
+	// Up and Down implement migrations in Go (see GoMigrationReader). If either is set,
+	// the migration is run by calling the function instead of executing the queries.
+	// The checksum of such a migration does not reflect its implementation.
+	Up   MigrationFunc
+	Down MigrationFunc
+}
+
+func (d Definition) isGoMigration() bool {
+	return d.Up != nil || d.Down != nil
+}
+
+// run executes the migration's up (or down) query or function.
+func (d Definition) run(ctx context.Context, db DB, reverse bool) error {
+	if d.isGoMigration() {
+		f := d.Up
+		if reverse {
+			f = d.Down
+		}
+		if f == nil {
+			return nil
+		}
+
+		return f(ctx, db)
+	}
+
+	query := d.UpQuery
+	if reverse {
+		query = d.DownQuery
+	}
+
+	return db.Exec(ctx, query)
+}
+
+// noTransaction returns true if the migration's up query, or its down query if reverse
+// is set, opts out of running in a transaction.
+func (d Definition) noTransaction(reverse bool) bool {
+	if reverse {
+		return d.NoTransactionDown
+	}
+
+	return d.NoTransactionUp
+}
+
+// Checksum returns a digest of the migration's up and down queries. Comments and
+// whitespace are normalized away so that cosmetic edits do not change the checksum.
+// Migrations implemented in Go have no queries, so the runner records no checksum
+// for them.
+func (d Definition) Checksum() string {
+	up, _ := d.UpQuery.Format()
+	down, _ := d.DownQuery.Format()
+
+	hash := sha256.New()
+	hash.Write([]byte(normalizeQuery(up)))
+	hash.Write([]byte{0})
+	hash.Write([]byte(normalizeQuery(down)))
+	return hex.EncodeToString(hash.Sum(nil))
 }
 
 type IndexMetadata struct {
@@ -29,11 +110,36 @@ func (f MigrationReaderFunc) ReadAll() ([]RawDefinition, error) {
 	return f()
 }
 
+// NewMultiMigrationReader returns a reader of the migrations of all the given readers,
+// ordered by identifier.
+func NewMultiMigrationReader(readers ...MigrationReader) MigrationReader {
+	return MigrationReaderFunc(func() (definitions []RawDefinition, _ error) {
+		for _, reader := range readers {
+			rawDefinitions, err := reader.ReadAll()
+			if err != nil {
+				return nil, err
+			}
+
+			definitions = append(definitions, rawDefinitions...)
+		}
+
+		sort.SliceStable(definitions, func(i, j int) bool { return definitions[i].ID < definitions[j].ID })
+		return definitions, nil
+	})
+}
+
 type RawDefinition struct {
-	ID           int
-	Name         string
-	RawUpQuery   string
-	RawDownQuery string
+	ID            int
+	Name          string
+	RawUpQuery    string
+	RawDownQuery  string
+	Description   string
+	Author        string
+	NoTransaction bool // applies to both the up and down queries
+	Parents       []int
+	Squashes      []int
+	Up            MigrationFunc
+	Down          MigrationFunc
 }
 
 var (
@@ -55,6 +161,8 @@ var (
 		capturedIdentifierPattern, // capture table name
 	}, ``)
 
+	noTransactionDirectivePattern = regexp.MustCompile(`(?m)^\s*--\s*pgutil:no-transaction\s*$`)
+
 	createIndexConcurrentlyPattern    = regexp.MustCompile(createIndexConcurrentlyPatternHead)
 	createIndexConcurrentlyPatternAll = regexp.MustCompile(createIndexConcurrentlyPatternHead + "[^;]+;")
 )
@@ -76,6 +184,10 @@ func ReadMigrations(reader MigrationReader) (definitions []Definition, _ error)
 		prunedUp := removeComments(rawDefinition.RawUpQuery)
 		prunedDown := removeComments(rawDefinition.RawDownQuery)
 
+		if (rawDefinition.Up != nil || rawDefinition.Down != nil) && (prunedUp != "" || prunedDown != "") {
+			return nil, fmt.Errorf("migration %d defines both queries and Go functions", rawDefinition.ID)
+		}
+
 		if matches := createIndexConcurrentlyPattern.FindStringSubmatch(prunedUp); len(matches) > 0 {
 			if strings.TrimSpace(createIndexConcurrentlyPatternAll.ReplaceAllString(prunedUp, "")) != "" {
 				return nil, fmt.Errorf(`"create index concurrently" is not the only statement in the up migration`)
@@ -92,21 +204,148 @@ func ReadMigrations(reader MigrationReader) (definitions []Definition, _ error)
 		}
 
 		definitions = append(definitions, Definition{
-			ID:            rawDefinition.ID,
-			Name:          rawDefinition.Name,
-			UpQuery:       RawQuery(rawDefinition.RawUpQuery),
-			DownQuery:     RawQuery(rawDefinition.RawDownQuery),
-			IndexMetadata: indexMetadata,
+			ID:                rawDefinition.ID,
+			Name:              rawDefinition.Name,
+			UpQuery:           RawQuery(rawDefinition.RawUpQuery),
+			DownQuery:         RawQuery(rawDefinition.RawDownQuery),
+			IndexMetadata:     indexMetadata,
+			NoTransactionUp:   rawDefinition.NoTransaction || noTransactionDirectivePattern.MatchString(rawDefinition.RawUpQuery),
+			NoTransactionDown: rawDefinition.NoTransaction || noTransactionDirectivePattern.MatchString(rawDefinition.RawDownQuery),
+			Description:       rawDefinition.Description,
+			Author:            rawDefinition.Author,
+			Parents:           rawDefinition.Parents,
+			Squashes:          rawDefinition.Squashes,
+			Up:                rawDefinition.Up,
+			Down:              rawDefinition.Down,
 		})
 	}
 
-	return definitions, nil
+	for _, definition := range definitions {
+		for _, squashedID := range definition.Squashes {
+			if _, ok := ids[squashedID]; ok && squashedID != definition.ID {
+				return nil, fmt.Errorf("migration %d squashes migration %d, which still exists", definition.ID, squashedID)
+			}
+		}
+	}
+
+	return sortDefinitions(definitions)
+}
+
+// sortDefinitions returns the given definitions in topological order, where ties
+// are broken by identifier. An error is returned if a parent does not exist or if
+// the parent relationships form a cycle.
+func sortDefinitions(definitions []Definition) ([]Definition, error) {
+	parents, err := definitionParents(definitions)
+	if err != nil {
+		return nil, err
+	}
+
+	definitionsByID := make(map[int]Definition, len(definitions))
+	for _, definition := range definitions {
+		definitionsByID[definition.ID] = definition
+	}
+
+	// Build a graph where nodes are migration identifiers and edges are the
+	// parents that have not yet been placed in the topological order.
+	graph := make(map[int]map[int]struct{}, len(definitions))
+	for id, parentIDs := range parents {
+		graph[id] = map[int]struct{}{}
+		for _, parentID := range parentIDs {
+			graph[id][parentID] = struct{}{}
+		}
+	}
+
+	topologicalOrder := make([]Definition, 0, len(definitions))
+	for len(graph) > 0 {
+		// Select the lowest identifier with no remaining dependencies. The number of
+		// migrations should be small enough that scanning the graph each time is fine.
+		top, found := 0, false
+		for id, edges := range graph {
+			if len(edges) == 0 && (!found || id < top) {
+				top, found = id, true
+			}
+		}
+		if !found {
+			ids := make([]int, 0, len(graph))
+			for id := range graph {
+				ids = append(ids, id)
+			}
+			sort.Ints(ids)
+
+			return nil, fmt.Errorf("migration parents form a cycle among migrations %v", ids)
+		}
+
+		topologicalOrder = append(topologicalOrder, definitionsByID[top])
+
+		delete(graph, top)
+		for _, edges := range graph {
+			delete(edges, top)
+		}
+	}
+
+	return topologicalOrder, nil
+}
+
+// definitionParents returns a map from each migration identifier to the identifiers
+// of its parents. Migrations that do not declare parents depend on the migration with
+// the next-lowest identifier.
+func definitionParents(definitions []Definition) (map[int][]int, error) {
+	ids := make([]int, 0, len(definitions))
+	for _, definition := range definitions {
+		ids = append(ids, definition.ID)
+	}
+	sort.Ints(ids)
+
+	exists := make(map[int]struct{}, len(ids))
+	for _, id := range ids {
+		exists[id] = struct{}{}
+	}
+
+	parents := make(map[int][]int, len(definitions))
+	for _, definition := range definitions {
+		if len(definition.Parents) == 0 {
+			if i := sort.SearchInts(ids, definition.ID); i > 0 {
+				parents[definition.ID] = []int{ids[i-1]}
+			} else {
+				parents[definition.ID] = nil
+			}
+
+			continue
+		}
+
+		for _, parentID := range definition.Parents {
+			if _, ok := exists[parentID]; !ok {
+				return nil, fmt.Errorf("migration %d has unknown parent %d", definition.ID, parentID)
+			}
+		}
+
+		parents[definition.ID] = definition.Parents
+	}
+
+	return parents, nil
+}
+
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(removeComments(query)), " ")
 }
 
 func removeComments(query string) string {
+	var uncommented strings.Builder
+	for _, segment := range splitSQL(query) {
+		switch segment.kind {
+		case sqlSegmentComment:
+			if strings.HasPrefix(segment.text, "/*") {
+				// Keep tokens on either side of a block comment apart
+				uncommented.WriteString(" ")
+			}
+		default:
+			uncommented.WriteString(segment.text)
+		}
+	}
+
 	var filtered []string
-	for _, line := range strings.Split(query, "\n") {
-		if line := strings.TrimSpace(strings.Split(line, "--")[0]); line != "" {
+	for _, line := range strings.Split(uncommented.String(), "\n") {
+		if line := strings.TrimSpace(line); line != "" {
 			filtered = append(filtered, line)
 		}
 	}
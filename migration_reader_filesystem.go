@@ -1,6 +1,7 @@
 package pgutil
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/fs"
@@ -10,6 +11,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 type FilesystemMigrationReader struct {
@@ -86,9 +89,55 @@ func (r *FilesystemMigrationReader) readDefinition(dirname string) (RawDefinitio
 		RawDownQuery: string(downFileContents),
 	}
 
+	metadataPath := path.Join(dirname, "metadata.yaml")
+	metadataFileContents, err := readFile(r.fs, metadataPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return definition, true, nil
+		}
+
+		return RawDefinition{}, false, err
+	}
+
+	metadata, err := parseMigrationMetadata(metadataFileContents)
+	if err != nil {
+		return RawDefinition{}, false, fmt.Errorf("malformed migration metadata %s: %w", metadataPath, err)
+	}
+
+	if metadata.Name != "" {
+		definition.Name = metadata.Name
+	}
+	definition.Description = metadata.Description
+	definition.Author = metadata.Author
+	definition.NoTransaction = metadata.NoTransaction
+	definition.Parents = metadata.Parents
+	definition.Squashes = metadata.Squashes
+
 	return definition, true, nil
 }
 
+// migrationMetadata is the schema of an optional metadata.yaml file alongside a
+// migration's up.sql and down.sql files.
+type migrationMetadata struct {
+	Name          string `yaml:"name"`
+	Description   string `yaml:"description"`
+	Author        string `yaml:"author"`
+	NoTransaction bool   `yaml:"no-transaction"`
+	Parents       []int  `yaml:"parents"`
+	Squashes      []int  `yaml:"squashes"`
+}
+
+func parseMigrationMetadata(contents []byte) (metadata migrationMetadata, _ error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&metadata); err != nil && err != io.EOF {
+		return migrationMetadata{}, err
+	}
+
+	return metadata, nil
+}
+
 func readFile(fs fs.FS, filepath string) ([]byte, error) {
 	file, err := fs.Open(filepath)
 	if err != nil {
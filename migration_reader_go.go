@@ -0,0 +1,46 @@
+package pgutil
+
+import (
+	"context"
+	"sync"
+)
+
+// MigrationFunc performs a migration implemented in Go. The given database handle is
+// the transaction in which the migration is applied, unless the migration opts out of
+// transactions.
+type MigrationFunc func(ctx context.Context, tx DB) error
+
+// GoMigrationReader is a MigrationReader of migrations implemented in Go. Combine it
+// with a reader of SQL migrations via NewMultiMigrationReader.
+type GoMigrationReader struct {
+	mu          sync.Mutex
+	definitions []RawDefinition
+}
+
+func NewGoMigrationReader() *GoMigrationReader {
+	return &GoMigrationReader{}
+}
+
+// Register adds a migration with the given identifier and up and down functions. A
+// nil function is a no-op. Identifiers must be unique across all readers combined.
+func (r *GoMigrationReader) Register(id int, name string, up, down MigrationFunc) {
+	r.RegisterDefinition(RawDefinition{ID: id, Name: name, Up: up, Down: down})
+}
+
+// RegisterDefinition adds the given migration. This allows Go migrations to declare
+// the same metadata as SQL migrations (e.g., parents).
+func (r *GoMigrationReader) RegisterDefinition(definition RawDefinition) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.definitions = append(r.definitions, definition)
+}
+
+func (r *GoMigrationReader) ReadAll() ([]RawDefinition, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	definitions := make([]RawDefinition, len(r.definitions))
+	copy(definitions, r.definitions)
+	return definitions, nil
+}
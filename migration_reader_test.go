@@ -1,6 +1,7 @@
 package pgutil
 
 import (
+	"context"
 	"path"
 	"testing"
 
@@ -60,6 +61,142 @@ func TestReadMigrations(t *testing.T) {
 		}, definitions[3])
 	})
 
+	t.Run("no-transaction directive", func(t *testing.T) {
+		definitions, err := ReadMigrations(MigrationReaderFunc(func() ([]RawDefinition, error) {
+			return []RawDefinition{
+				{ID: 1, RawUpQuery: "-- pgutil:no-transaction\nVACUUM users;", RawDownQuery: "-- no-op"},
+				{ID: 2, RawUpQuery: "CREATE TABLE t();", RawDownQuery: "  --  pgutil:no-transaction  \nVACUUM t;"},
+				{ID: 3, RawUpQuery: "CREATE TABLE t(); -- pgutil:no-transaction", RawDownQuery: "DROP TABLE t;"},
+			}, nil
+		}))
+		require.NoError(t, err)
+		require.Len(t, definitions, 3)
+
+		assert.True(t, definitions[0].NoTransactionUp)
+		assert.False(t, definitions[0].NoTransactionDown)
+		assert.False(t, definitions[1].NoTransactionUp)
+		assert.True(t, definitions[1].NoTransactionDown)
+		assert.False(t, definitions[2].NoTransactionUp)
+		assert.False(t, definitions[2].NoTransactionDown)
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		definitions, err := ReadMigrations(NewFilesystemMigrationReader(path.Join("testdata", "migrations", "metadata")))
+		require.NoError(t, err)
+		require.Len(t, definitions, 4)
+
+		assert.Equal(t, "first", definitions[0].Name)
+		assert.Nil(t, definitions[0].Parents)
+
+		assert.Equal(t, 20240101000000, definitions[1].ID)
+		assert.Equal(t, "add users table", definitions[1].Name)
+		assert.Equal(t, "Adds the users table.", definitions[1].Description)
+		assert.Equal(t, "alice", definitions[1].Author)
+		assert.Equal(t, []int{1}, definitions[1].Parents)
+		assert.False(t, definitions[1].NoTransactionUp)
+		assert.False(t, definitions[1].NoTransactionDown)
+
+		assert.Equal(t, 20240102000000, definitions[2].ID)
+		assert.Equal(t, "branch b", definitions[2].Name)
+		assert.Equal(t, "bob", definitions[2].Author)
+		assert.True(t, definitions[2].NoTransactionUp)
+		assert.True(t, definitions[2].NoTransactionDown)
+
+		assert.Equal(t, 20240103000000, definitions[3].ID)
+		assert.Equal(t, []int{20240101000000, 20240102000000}, definitions[3].Parents)
+	})
+
+	t.Run("squashed", func(t *testing.T) {
+		definitions, err := ReadMigrations(NewFilesystemMigrationReader(path.Join("testdata", "migrations", "squashed")))
+		require.NoError(t, err)
+		require.Len(t, definitions, 2)
+
+		assert.Equal(t, 3, definitions[0].ID)
+		assert.Equal(t, []int{1, 2, 3}, definitions[0].Squashes)
+		assert.Nil(t, definitions[1].Squashes)
+	})
+
+	t.Run("squashed migration exists", func(t *testing.T) {
+		_, err := ReadMigrations(MigrationReaderFunc(func() ([]RawDefinition, error) {
+			return []RawDefinition{{ID: 2}, {ID: 3, Squashes: []int{1, 2, 3}}}, nil
+		}))
+		assert.ErrorContains(t, err, "migration 3 squashes migration 2, which still exists")
+	})
+
+	t.Run("go migrations", func(t *testing.T) {
+		goReader := NewGoMigrationReader()
+		goReader.Register(2, "backfill", func(ctx context.Context, tx DB) error { return nil }, nil)
+
+		definitions, err := ReadMigrations(NewMultiMigrationReader(
+			NewFilesystemMigrationReader(path.Join("testdata", "migrations", "valid")),
+			goReader,
+		))
+		assert.ErrorContains(t, err, "duplicate migration identifier 2")
+		assert.Nil(t, definitions)
+
+		goReader = NewGoMigrationReader()
+		goReader.Register(4, "backfill", func(ctx context.Context, tx DB) error { return nil }, nil)
+
+		definitions, err = ReadMigrations(NewMultiMigrationReader(
+			NewFilesystemMigrationReader(path.Join("testdata", "migrations", "valid")),
+			goReader,
+		))
+		require.NoError(t, err)
+		require.Len(t, definitions, 4)
+		assert.Equal(t, 4, definitions[3].ID)
+		assert.NotNil(t, definitions[3].Up)
+		assert.Nil(t, definitions[3].Down)
+	})
+
+	t.Run("go migration with queries", func(t *testing.T) {
+		_, err := ReadMigrations(MigrationReaderFunc(func() ([]RawDefinition, error) {
+			return []RawDefinition{{
+				ID:         1,
+				RawUpQuery: "SELECT 1;",
+				Up:         func(ctx context.Context, tx DB) error { return nil },
+			}}, nil
+		}))
+		assert.ErrorContains(t, err, "migration 1 defines both queries and Go functions")
+	})
+
+	t.Run("malformed metadata", func(t *testing.T) {
+		_, err := ReadMigrations(NewFilesystemMigrationReader(path.Join("testdata", "migrations", "malformed_metadata")))
+		assert.ErrorContains(t, err, "malformed migration metadata 1_first/metadata.yaml")
+	})
+
+	t.Run("topological order", func(t *testing.T) {
+		definitions, err := ReadMigrations(MigrationReaderFunc(func() ([]RawDefinition, error) {
+			return []RawDefinition{
+				{ID: 1},
+				{ID: 2, Parents: []int{4}},
+				{ID: 3, Parents: []int{1}},
+				{ID: 4, Parents: []int{1}},
+				{ID: 5},
+			}, nil
+		}))
+		require.NoError(t, err)
+
+		var ids []int
+		for _, definition := range definitions {
+			ids = append(ids, definition.ID)
+		}
+		assert.Equal(t, []int{1, 3, 4, 2, 5}, ids)
+	})
+
+	t.Run("missing parent", func(t *testing.T) {
+		_, err := ReadMigrations(MigrationReaderFunc(func() ([]RawDefinition, error) {
+			return []RawDefinition{{ID: 1}, {ID: 2, Parents: []int{3}}}, nil
+		}))
+		assert.ErrorContains(t, err, "migration 2 has unknown parent 3")
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		_, err := ReadMigrations(MigrationReaderFunc(func() ([]RawDefinition, error) {
+			return []RawDefinition{{ID: 1}, {ID: 2, Parents: []int{3}}, {ID: 3, Parents: []int{2}}, {ID: 4}}, nil
+		}))
+		assert.ErrorContains(t, err, "migration parents form a cycle among migrations [2 3 4]")
+	})
+
 	t.Run("duplicate identifiers", func(t *testing.T) {
 		_, err := ReadMigrations(NewFilesystemMigrationReader(path.Join("testdata", "migrations", "duplicate_identifiers")))
 		assert.ErrorContains(t, err, "duplicate migration identifier 2")
@@ -75,3 +212,20 @@ func TestReadMigrations(t *testing.T) {
 		assert.ErrorContains(t, err, `"create index concurrently" is not the only statement in the up migration`)
 	})
 }
+
+func TestDefinitionChecksum(t *testing.T) {
+	checksum := func(up, down string) string {
+		return Definition{UpQuery: RawQuery(up), DownQuery: RawQuery(down)}.Checksum()
+	}
+
+	base := checksum("CREATE TABLE users (id SERIAL PRIMARY KEY);", "DROP TABLE users;")
+	assert.Equal(t, base, checksum("-- Create users\nCREATE TABLE users\n    (id SERIAL PRIMARY KEY);\n", "DROP   TABLE users;  -- drop"))
+	assert.NotEqual(t, base, checksum("CREATE TABLE users (id BIGSERIAL PRIMARY KEY);", "DROP TABLE users;"))
+	assert.NotEqual(t, base, checksum("CREATE TABLE users (id SERIAL PRIMARY KEY);", "DROP TABLE IF EXISTS users;"))
+	assert.NotEqual(t, checksum("SELECT 1;", "SELECT 2;"), checksum("SELECT 1; SELECT 2;", ""))
+
+	// Comment markers within quotes are part of the query
+	assert.Equal(t, checksum("SELECT /* one */ 1;", ""), checksum("SELECT 1;", ""))
+	assert.NotEqual(t, checksum("SELECT '--a';", ""), checksum("SELECT '--b';", ""))
+	assert.NotEqual(t, checksum("SELECT $$ -- a $$;", ""), checksum("SELECT $$ -- b $$;", ""))
+}
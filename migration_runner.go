@@ -3,12 +3,14 @@ package pgutil
 import (
 	"context"
 	"errors"
+	"fmt"
 	"slices"
 	"time"
 
 	"github.com/go-nacelle/log/v2"
 	"github.com/go-nacelle/nacelle/v2"
 	"github.com/jackc/pgconn"
+	"github.com/lib/pq"
 )
 
 type Runner struct {
@@ -16,9 +18,16 @@ type Runner struct {
 	logger      nacelle.Logger
 	definitions []Definition
 	locker      *TransactionalLocker
+
+	checksumMismatchPolicy ChecksumMismatchPolicy
+
+	// Set only when the database supports pinned connections
+	sessionLocker *SessionLocker
 }
 
-func NewMigrationRunner(db DB, reader MigrationReader, logger nacelle.Logger) (*Runner, error) {
+func NewMigrationRunner(db DB, reader MigrationReader, logger nacelle.Logger, configs ...RunnerConfigFunc) (*Runner, error) {
+	options := getRunnerOptions(configs)
+
 	definitions, err := ReadMigrations(reader)
 	if err != nil {
 		return nil, err
@@ -29,11 +38,18 @@ func NewMigrationRunner(db DB, reader MigrationReader, logger nacelle.Logger) (*
 		return nil, err
 	}
 
+	sessionLocker, err := NewSessionLocker(db, StringKey("nacelle/pgutil.migration-runner"))
+	if err != nil && !errors.Is(err, ErrSessionLockerUnsupported) {
+		return nil, err
+	}
+
 	return &Runner{
-		db:          db,
-		logger:      logger,
-		definitions: definitions,
-		locker:      locker,
+		db:                     db,
+		logger:                 logger,
+		definitions:            definitions,
+		locker:                 locker,
+		checksumMismatchPolicy: options.checksumMismatchPolicy,
+		sessionLocker:          sessionLocker,
 	}, nil
 }
 
@@ -41,18 +57,62 @@ func (r *Runner) Definitions() []Definition {
 	return r.definitions
 }
 
+// MigrationTarget describes the migrations affected by a call to ApplyAll, Apply, or
+// Undo. See ApplyAllTarget, ApplyTarget, and UndoTarget.
+type MigrationTarget struct {
+	all     bool
+	id      int
+	reverse bool
+}
+
+// ApplyAllTarget targets all migrations, as applied by ApplyAll.
+func ApplyAllTarget() MigrationTarget {
+	return MigrationTarget{all: true}
+}
+
+// ApplyTarget targets the migration with the given identifier along with all of its
+// (transitive) parents, as applied by Apply.
+func ApplyTarget(id int) MigrationTarget {
+	return MigrationTarget{id: id}
+}
+
+// UndoTarget targets the migration with the given identifier along with all of its
+// (transitive) children, as undone by Undo.
+func UndoTarget(id int) MigrationTarget {
+	return MigrationTarget{id: id, reverse: true}
+}
+
+// targetDefinitions returns the definitions affected by the given target in the order in
+// which they would be applied (or undone).
+func (r *Runner) targetDefinitions(target MigrationTarget) ([]Definition, error) {
+	if target.all {
+		return r.definitions, nil
+	}
+
+	definitions, ok := r.closure(target.id, target.reverse)
+	if !ok {
+		return nil, errors.New("migration not found")
+	}
+	if target.reverse {
+		slices.Reverse(definitions)
+	}
+
+	return definitions, nil
+}
+
 func (r *Runner) ApplyAll(ctx context.Context) error {
 	return r.apply(ctx, r.definitions)
 }
 
+// Apply applies the migration with the given identifier along with all of its
+// (transitive) parents.
 func (r *Runner) Apply(ctx context.Context, id int) error {
-	for i, definition := range r.definitions {
-		if definition.ID == id {
-			return r.apply(ctx, r.definitions[:i+1])
-		}
+	definitions, err := r.targetDefinitions(ApplyTarget(id))
+	if err != nil {
+		return err
 	}
 
-	return errors.New("migration not found")
+	return r.apply(ctx, definitions)
 }
 
 func (r *Runner) apply(ctx context.Context, definitions []Definition) error {
@@ -61,36 +121,161 @@ func (r *Runner) apply(ctx context.Context, definitions []Definition) error {
 	}
 
 	for {
-		upToDate, cicDefinition, err := r.applyDefinitions(ctx, definitions, false)
+		upToDate, deferredDefinition, err := r.applyDefinitions(ctx, definitions, false)
 		if err != nil || upToDate {
 			return err
 		}
 
-		if cicDefinition != nil {
-			if err := r.applyConcurrentIndexCreation(ctx, *cicDefinition); err != nil {
+		if deferredDefinition != nil {
+			if deferredDefinition.IndexMetadata != nil {
+				err = r.applyConcurrentIndexCreation(ctx, *deferredDefinition)
+			} else {
+				err = r.applyWithoutTransaction(ctx, *deferredDefinition, false)
+			}
+			if err != nil {
 				return err
 			}
 		}
 	}
 }
 
+// Undo reverts the migration with the given identifier along with all of its
+// (transitive) children.
 func (r *Runner) Undo(ctx context.Context, id int) error {
 	if err := r.ensureMigrationLogsTable(ctx); err != nil {
 		return err
 	}
 
-	for i, definition := range r.definitions {
-		if definition.ID == id {
-			definitions := slices.Clone(r.definitions[i:])
-			slices.Reverse(definitions)
+	definitions, err := r.targetDefinitions(UndoTarget(id))
+	if err != nil {
+		return err
+	}
 
-			// NOTE: CIC are illegal in down migrations; perform this in one shot
-			_, _, err := r.applyDefinitions(ctx, definitions, true)
+	for {
+		// NOTE: CIC are illegal in down migrations, so only migrations opting
+		// out of transactions are deferred
+		upToDate, deferredDefinition, err := r.applyDefinitions(ctx, definitions, true)
+		if err != nil || upToDate {
 			return err
 		}
+
+		if deferredDefinition != nil {
+			if err := r.applyWithoutTransaction(ctx, *deferredDefinition, true); err != nil {
+				return err
+			}
+		}
 	}
+}
+
+// PlannedMigration is a migration that would be applied or undone.
+type PlannedMigration struct {
+	Definition Definition
+	Reverse    bool
 
-	return errors.New("migration not found")
+	// Query is the up query of the migration, or the down query if Reverse is set. This
+	// is empty for migrations implemented in Go.
+	Query Q
+
+	// ConcurrentIndexCreation indicates that the migration creates an index concurrently,
+	// which is performed outside of the DDL lock and monitored until the index is valid.
+	ConcurrentIndexCreation bool
+
+	// NoTransaction indicates that the query is not wrapped in a transaction and is run
+	// under a session-level lock instead.
+	NoTransaction bool
+}
+
+// Plan returns the migrations that would be applied or undone for the given target,
+// in order, without applying them. Plan does not modify the database, including the
+// migration_logs table. The DDL lock is not taken, so the plan may be stale by the
+// time it is acted upon. Plan fails under the same conditions as the
+// corresponding ApplyAll, Apply, or Undo call would (e.g., modified migrations).
+func (r *Runner) Plan(ctx context.Context, target MigrationTarget) ([]PlannedMigration, error) {
+	definitions, err := r.targetDefinitions(target)
+	if err != nil {
+		return nil, err
+	}
+
+	migrationLogs, err := r.existingMigrationLogs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.checkModifiedMigrations(migrationLogs); err != nil {
+		return nil, err
+	}
+
+	migrationsToApply := filterDefinitions(definitions, migrationLogs, target.reverse)
+	if !target.reverse {
+		if err := checkPartiallySquashed(migrationsToApply, migrationLogs); err != nil {
+			return nil, err
+		}
+	}
+
+	var plan []PlannedMigration
+	for _, definition := range migrationsToApply {
+		query := definition.UpQuery
+		if target.reverse {
+			query = definition.DownQuery
+		}
+
+		plan = append(plan, PlannedMigration{
+			Definition:              definition,
+			Reverse:                 target.reverse,
+			Query:                   query,
+			ConcurrentIndexCreation: definition.IndexMetadata != nil && !target.reverse,
+			NoTransaction:           definition.noTransaction(target.reverse),
+		})
+	}
+
+	return plan, nil
+}
+
+// closure returns the migration with the given identifier along with all of its
+// (transitive) parents, or all of its (transitive) children if descendants is true.
+// Definitions are returned in topological order.
+func (r *Runner) closure(id int, descendants bool) ([]Definition, bool) {
+	parents, err := definitionParents(r.definitions)
+	if err != nil {
+		// Definitions were validated on construction
+		panic(err.Error())
+	}
+
+	edges := parents
+	if descendants {
+		edges = map[int][]int{}
+		for childID, parentIDs := range parents {
+			for _, parentID := range parentIDs {
+				edges[parentID] = append(edges[parentID], childID)
+			}
+		}
+	}
+
+	if _, ok := parents[id]; !ok {
+		return nil, false
+	}
+
+	included := map[int]struct{}{}
+	frontier := []int{id}
+	for len(frontier) > 0 {
+		next := frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+
+		if _, ok := included[next]; ok {
+			continue
+		}
+		included[next] = struct{}{}
+		frontier = append(frontier, edges[next]...)
+	}
+
+	var definitions []Definition
+	for _, definition := range r.definitions {
+		if _, ok := included[definition.ID]; ok {
+			definitions = append(definitions, definition)
+		}
+	}
+
+	return definitions, true
 }
 
 func (r *Runner) ensureMigrationLogsTable(ctx context.Context) error {
@@ -103,6 +288,20 @@ func (r *Runner) ensureMigrationLogsTable(ctx context.Context) error {
 		"ALTER TABLE migration_logs ADD COLUMN IF NOT EXISTS finished_at timestamptz",
 		"ALTER TABLE migration_logs ADD COLUMN IF NOT EXISTS success boolean",
 		"ALTER TABLE migration_logs ADD COLUMN IF NOT EXISTS error_message text",
+		"ALTER TABLE migration_logs ADD COLUMN IF NOT EXISTS checksum text",
+		"ALTER TABLE migration_logs ADD COLUMN IF NOT EXISTS squashes bigint[]",
+
+		// Timestamp-based migration identifiers (e.g., YYYYMMDDHHMMSS) do not fit in an integer.
+		// Widen the column only if necessary to avoid taking an exclusive lock on each run.
+		`DO $$ BEGIN
+			IF (
+				SELECT data_type
+				FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = 'migration_logs' AND column_name = 'migration_id'
+			) = 'integer' THEN
+				ALTER TABLE migration_logs ALTER COLUMN migration_id TYPE bigint;
+			END IF;
+		END $$`,
 	} {
 		if err := r.db.Exec(ctx, RawQuery(query)); err != nil {
 			return err
@@ -112,24 +311,25 @@ func (r *Runner) ensureMigrationLogsTable(ctx context.Context) error {
 	return nil
 }
 
-func (r *Runner) applyDefinitions(ctx context.Context, definitions []Definition, reverse bool) (upToDate bool, cicDefinition *Definition, _ error) {
+// applyDefinitions applies the given definitions that are not yet in the target
+// state while holding the DDL lock. Application stops at the first definition that
+// must be applied without holding the (transactional) DDL lock, which is returned
+// to the caller to be applied separately.
+func (r *Runner) applyDefinitions(ctx context.Context, definitions []Definition, reverse bool) (upToDate bool, deferredDefinition *Definition, _ error) {
 	err := r.locker.WithLock(ctx, StringKey("ddl"), func(_ DB) (err error) {
 		migrationLogs, err := r.MigrationLogs(ctx)
 		if err != nil {
 			return err
 		}
 
-		applied := map[int]struct{}{}
-		for _, log := range migrationLogs {
-			if log.Success != nil && *log.Success && !log.Reverse {
-				applied[log.MigrationID] = struct{}{}
-			}
+		if err := r.checkModifiedMigrations(migrationLogs); err != nil {
+			return err
 		}
 
-		var migrationsToApply []Definition
-		for _, definition := range definitions {
-			if _, ok := applied[definition.ID]; ok == reverse {
-				migrationsToApply = append(migrationsToApply, definition)
+		migrationsToApply := filterDefinitions(definitions, migrationLogs, reverse)
+		if !reverse {
+			if err := checkPartiallySquashed(migrationsToApply, migrationLogs); err != nil {
+				return err
 			}
 		}
 
@@ -144,14 +344,22 @@ func (r *Runner) applyDefinitions(ctx context.Context, definitions []Definition,
 				// We can't perform CIC while holding a lock or else we'll deadlock.
 				// Capture this definition to be applied outside of the lock we're holding.
 				// We can skip this check for reverse application as CIC are illegal in down migrations.
-				cicDefinition = &definition
+				deferredDefinition = &definition
+				return nil
+			}
+
+			if definition.noTransaction(reverse) {
+				// The DDL lock is held by an open transaction, which statements such as
+				// REINDEX CONCURRENTLY would wait on. Capture this definition to be applied
+				// under a session-level lock instead.
+				deferredDefinition = &definition
 				return nil
 			}
 
 			if err := r.withMigrationLog(ctx, definition, reverse, func(_ int) error {
-				query, direction := definition.UpQuery, "up"
+				direction := "up"
 				if reverse {
-					query, direction = definition.DownQuery, "down"
+					direction = "down"
 				}
 
 				logger := r.logger.WithFields(log.LogFields{
@@ -161,7 +369,7 @@ func (r *Runner) applyDefinitions(ctx context.Context, definitions []Definition,
 				})
 				logger.Info("Applying migration")
 
-				if err := r.db.WithTransaction(ctx, func(tx DB) error { return tx.Exec(ctx, query) }); err != nil {
+				if err := r.db.WithTransaction(ctx, func(tx DB) error { return definition.run(ctx, tx, reverse) }); err != nil {
 					logger.ErrorWithFields(log.LogFields{"error": err}, "Failed to apply migration")
 					return err
 				}
@@ -175,7 +383,92 @@ func (r *Runner) applyDefinitions(ctx context.Context, definitions []Definition,
 		return nil
 	})
 
-	return upToDate, cicDefinition, err
+	return upToDate, deferredDefinition, err
+}
+
+func filterDefinitions(definitions []Definition, migrationLogs map[int]MigrationLog, reverse bool) []Definition {
+	applied := map[int]struct{}{}
+	for _, log := range migrationLogs {
+		if log.Success != nil && *log.Success && !log.Reverse {
+			applied[log.MigrationID] = struct{}{}
+		}
+	}
+
+	var migrationsToApply []Definition
+	for _, definition := range definitions {
+		if _, ok := applied[definition.ID]; ok == reverse {
+			migrationsToApply = append(migrationsToApply, definition)
+		}
+	}
+
+	return migrationsToApply
+}
+
+// checkPartiallySquashed returns an error if any of the given baseline migrations to
+// be applied squash a migration that has already been applied. Such a database is
+// neither empty nor up to date with the baseline, so the baseline can't be applied.
+func checkPartiallySquashed(migrationsToApply []Definition, migrationLogs map[int]MigrationLog) error {
+	for _, definition := range migrationsToApply {
+		var applied []int
+		for _, squashedID := range definition.Squashes {
+			if log, ok := migrationLogs[squashedID]; ok && log.Success != nil && *log.Success && !log.Reverse {
+				applied = append(applied, squashedID)
+			}
+		}
+
+		if len(applied) > 0 {
+			return fmt.Errorf(
+				"cannot apply baseline migration %d: the migrations it squashes are partially applied (%v); apply them with a release predating the baseline first",
+				definition.ID,
+				applied,
+			)
+		}
+	}
+
+	return nil
+}
+
+// applyWithoutTransaction applies a migration that opted out of running in a
+// transaction. The DDL lock is taken as a session-level advisory lock on a dedicated
+// connection, which excludes other runners (including those holding the lock in a
+// transaction) without holding a transaction open for the migration's duration.
+func (r *Runner) applyWithoutTransaction(ctx context.Context, definition Definition, reverse bool) error {
+	if r.sessionLocker == nil {
+		return fmt.Errorf("migration %d opts out of transactions, which requires a database created by Dial", definition.ID)
+	}
+
+	return r.sessionLocker.WithLock(ctx, StringKey("ddl"), func(ctx context.Context) error {
+		migrationLogs, err := r.MigrationLogs(ctx)
+		if err != nil {
+			return err
+		}
+
+		if len(filterDefinitions([]Definition{definition}, migrationLogs, reverse)) == 0 {
+			// Applied by another runner while we were waiting for the lock
+			return nil
+		}
+
+		return r.withMigrationLog(ctx, definition, reverse, func(_ int) error {
+			direction := "up"
+			if reverse {
+				direction = "down"
+			}
+
+			logger := r.logger.WithFields(log.LogFields{
+				"id":        definition.ID,
+				"name":      definition.Name,
+				"direction": direction,
+			})
+			logger.Info("Applying migration without a transaction")
+
+			if err := definition.run(ctx, r.db, reverse); err != nil {
+				logger.ErrorWithFields(log.LogFields{"error": err}, "Failed to apply migration")
+				return err
+			}
+
+			return nil
+		})
+	})
 }
 
 func (r *Runner) applyConcurrentIndexCreation(ctx context.Context, definition Definition) error {
@@ -259,11 +552,13 @@ func (r *Runner) handleValidIndex(ctx context.Context, definition Definition) (r
 		}
 		if !ok {
 			if err := tx.Exec(ctx, Query(`
-				INSERT INTO migration_logs (migration_id, reverse, finished_at, success)
-				VALUES ({:id}, false, current_timestamp, true)
-			`,
-				Args{"id": definition.ID},
-			)); err != nil {
+				INSERT INTO migration_logs (migration_id, reverse, finished_at, success, checksum, squashes)
+				VALUES ({:id}, false, current_timestamp, true, {:checksum}, {:squashes})
+			`, Args{
+				"id":       definition.ID,
+				"checksum": checksumValue(definition),
+				"squashes": squashesArray(definition),
+			})); err != nil {
 				return err
 			}
 
@@ -354,19 +649,100 @@ type MigrationLog struct {
 	Reverse      bool
 	Success      *bool
 	ErrorMessage *string
+
+	// Checksum is the checksum of the migration's definition at the time it was run
+	// (see Definition.Checksum). This is nil for migrations implemented in Go and for
+	// logs written by earlier versions.
+	Checksum *string
+
+	// Squashes lists the migrations squashed by the baseline that was run. This is
+	// nil for logs of ordinary migrations and for logs written by earlier versions.
+	Squashes []int
 }
 
 var scanMigrationLogs = NewSliceScanner(func(s Scanner) (ms MigrationLog, _ error) {
-	err := s.Scan(&ms.MigrationID, &ms.Reverse, &ms.Success, &ms.ErrorMessage)
-	return ms, err
+	var squashes []int64
+	if err := s.Scan(&ms.MigrationID, &ms.Reverse, &ms.Success, &ms.ErrorMessage, &ms.Checksum, pq.Array(&squashes)); err != nil {
+		return ms, err
+	}
+
+	for _, id := range squashes {
+		ms.Squashes = append(ms.Squashes, int(id))
+	}
+
+	return ms, nil
 })
 
+// checksumValue returns the value written to the checksum column of the given
+// definition's migration logs. Changes to Go migrations cannot be detected, so their
+// checksum is NULL.
+func checksumValue(definition Definition) any {
+	if definition.isGoMigration() {
+		return nil
+	}
+
+	return definition.Checksum()
+}
+
+// squashesArray returns the value written to the squashes column of the given
+// definition's migration logs.
+func squashesArray(definition Definition) any {
+	if len(definition.Squashes) == 0 {
+		return nil
+	}
+
+	squashes := make([]int64, 0, len(definition.Squashes))
+	for _, id := range definition.Squashes {
+		squashes = append(squashes, int64(id))
+	}
+
+	return pq.Array(squashes)
+}
+
 func (r *Runner) MigrationLogs(ctx context.Context) (map[int]MigrationLog, error) {
 	if err := r.ensureMigrationLogsTable(ctx); err != nil {
 		return nil, err
 	}
 
-	migrationLogs, err := scanMigrationLogs(r.db.Query(ctx, RawQuery(`
+	return r.queryMigrationLogs(ctx, true, true)
+}
+
+// existingMigrationLogs returns the same logs as MigrationLogs without creating or
+// altering the migration_logs table. A missing table is treated as empty.
+func (r *Runner) existingMigrationLogs(ctx context.Context) (map[int]MigrationLog, error) {
+	exists, _, err := ScanBool(r.db.Query(ctx, RawQuery(`SELECT to_regclass('migration_logs') IS NOT NULL`)))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return map[int]MigrationLog{}, nil
+	}
+
+	// Tables written by earlier versions may lack the checksum and squashes columns
+	columns, err := ScanStrings(r.db.Query(ctx, RawQuery(`
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'migration_logs'
+	`)))
+	if err != nil {
+		return nil, err
+	}
+
+	return r.queryMigrationLogs(ctx, slices.Contains(columns, "checksum"), slices.Contains(columns, "squashes"))
+}
+
+func (r *Runner) queryMigrationLogs(ctx context.Context, hasChecksum, hasSquashes bool) (map[int]MigrationLog, error) {
+	checksumColumn := "checksum"
+	if !hasChecksum {
+		checksumColumn = "NULL::text"
+	}
+	squashesColumn := "squashes"
+	if !hasSquashes {
+		squashesColumn = "NULL::bigint[]"
+	}
+
+	// NOTE: Must interpolate identifier here as placeholders aren't valid in this position.
+	migrationLogs, err := scanMigrationLogs(r.db.Query(ctx, queryf(`
 		WITH ranked_migration_logs AS (
 			SELECT
 				l.*,
@@ -377,11 +753,13 @@ func (r *Runner) MigrationLogs(ctx context.Context) (map[int]MigrationLog, error
 			migration_id,
 			reverse,
 			success,
-			error_message
+			error_message,
+			%s,
+			%s
 		FROM ranked_migration_logs
 		WHERE rank = 1
 		ORDER BY migration_id
-	`)))
+	`, checksumColumn, squashesColumn)))
 	if err != nil {
 		return nil, err
 	}
@@ -419,12 +797,14 @@ func (r *Runner) WriteMigrationLog(ctx context.Context, id int) error {
 
 func (r *Runner) withMigrationLog(ctx context.Context, definition Definition, reverse bool, f func(id int) error) (err error) {
 	id, _, err := ScanInt(r.db.Query(ctx, Query(`
-		INSERT INTO migration_logs (migration_id, reverse)
-		VALUES ({:id}, {:reverse})
+		INSERT INTO migration_logs (migration_id, reverse, checksum, squashes)
+		VALUES ({:id}, {:reverse}, {:checksum}, {:squashes})
 		RETURNING id
 	`, Args{
-		"id":      definition.ID,
-		"reverse": reverse,
+		"id":       definition.ID,
+		"reverse":  reverse,
+		"checksum": checksumValue(definition),
+		"squashes": squashesArray(definition),
 	})))
 	if err != nil {
 		return err
@@ -448,6 +828,107 @@ func (r *Runner) withMigrationLog(ctx context.Context, definition Definition, re
 	return f(id)
 }
 
+// OutOfOrderMigrations returns the definitions that have not been applied but have
+// an identifier lower than that of an applied migration. This commonly happens when
+// branches that each added a migration are merged and deployed in a different order
+// than the migrations were created. The database is not modified.
+func (r *Runner) OutOfOrderMigrations(ctx context.Context) ([]Definition, error) {
+	migrationLogs, err := r.existingMigrationLogs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAppliedID, anyApplied := 0, false
+	for _, log := range migrationLogs {
+		if log.Success != nil && *log.Success && !log.Reverse {
+			if !anyApplied || log.MigrationID > maxAppliedID {
+				maxAppliedID, anyApplied = log.MigrationID, true
+			}
+		}
+	}
+	if !anyApplied {
+		return nil, nil
+	}
+
+	var outOfOrder []Definition
+	for _, definition := range filterDefinitions(r.definitions, migrationLogs, false) {
+		if definition.ID < maxAppliedID {
+			outOfOrder = append(outOfOrder, definition)
+		}
+	}
+
+	return outOfOrder, nil
+}
+
+// ChecksumMismatchError is returned by a migration runner when the definitions of
+// applied migrations differ from the definitions that were applied.
+type ChecksumMismatchError struct {
+	MigrationIDs []int
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("applied migrations have been modified: %v", e.MigrationIDs)
+}
+
+// ModifiedMigrations returns the applied definitions whose checksum differs from the
+// checksum recorded when they were applied. Migrations applied before checksums were
+// recorded are never considered modified. The database is not modified.
+func (r *Runner) ModifiedMigrations(ctx context.Context) ([]Definition, error) {
+	migrationLogs, err := r.existingMigrationLogs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.modifiedMigrations(migrationLogs), nil
+}
+
+func (r *Runner) modifiedMigrations(migrationLogs map[int]MigrationLog) []Definition {
+	var modified []Definition
+	for _, definition := range r.definitions {
+		log, ok := migrationLogs[definition.ID]
+		if !ok || log.Success == nil || !*log.Success || log.Reverse || log.Checksum == nil || definition.isGoMigration() {
+			continue
+		}
+		if len(definition.Squashes) > 0 && !slices.Equal(log.Squashes, definition.Squashes) {
+			// A baseline shares its identifier with the last migration it squashes. A
+			// log that does not record the baseline's squashes predates the baseline
+			// and was written by a different definition.
+			continue
+		}
+
+		if *log.Checksum != definition.Checksum() {
+			modified = append(modified, definition)
+		}
+	}
+
+	return modified
+}
+
+// checkModifiedMigrations reacts to modified applied migrations according to the
+// runner's checksum mismatch policy.
+func (r *Runner) checkModifiedMigrations(migrationLogs map[int]MigrationLog) error {
+	if r.checksumMismatchPolicy == ChecksumMismatchIgnore {
+		return nil
+	}
+
+	modified := r.modifiedMigrations(migrationLogs)
+	if len(modified) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(modified))
+	for _, definition := range modified {
+		ids = append(ids, definition.ID)
+	}
+
+	if r.checksumMismatchPolicy == ChecksumMismatchWarn {
+		r.logger.WarningWithFields(log.LogFields{"ids": ids}, "Applied migrations have been modified")
+		return nil
+	}
+
+	return &ChecksumMismatchError{MigrationIDs: ids}
+}
+
 //
 //
 
@@ -0,0 +1,46 @@
+package pgutil
+
+type (
+	runnerOptions struct {
+		checksumMismatchPolicy ChecksumMismatchPolicy
+	}
+
+	// RunnerConfigFunc is a function used to configure a migration runner.
+	RunnerConfigFunc func(*runnerOptions)
+)
+
+// ChecksumMismatchPolicy controls how a migration runner reacts to applied migrations
+// whose contents have changed since they were applied.
+type ChecksumMismatchPolicy int
+
+const (
+	// ChecksumMismatchRefuse causes the runner to return a *ChecksumMismatchError
+	// before applying or undoing any migrations.
+	ChecksumMismatchRefuse ChecksumMismatchPolicy = iota
+
+	// ChecksumMismatchWarn causes the runner to log a warning and proceed.
+	ChecksumMismatchWarn
+
+	// ChecksumMismatchIgnore causes the runner to proceed silently.
+	ChecksumMismatchIgnore
+)
+
+func getRunnerOptions(configs []RunnerConfigFunc) *runnerOptions {
+	options := &runnerOptions{
+		checksumMismatchPolicy: ChecksumMismatchRefuse,
+	}
+
+	for _, f := range configs {
+		f(options)
+	}
+
+	return options
+}
+
+// WithRunnerChecksumMismatchPolicy sets how the runner reacts to applied migrations
+// that have been modified. By default, the runner refuses to proceed.
+func WithRunnerChecksumMismatchPolicy(policy ChecksumMismatchPolicy) RunnerConfigFunc {
+	return func(o *runnerOptions) {
+		o.checksumMismatchPolicy = policy
+	}
+}
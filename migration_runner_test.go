@@ -2,6 +2,9 @@ package pgutil
 
 import (
 	"context"
+	"errors"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -312,3 +315,510 @@ func TestUndo(t *testing.T) {
 		require.ErrorContains(t, err, "column \"created_at\" does not exist")
 	})
 }
+
+func TestApplyWithoutTransaction(t *testing.T) {
+	definitions := []RawDefinition{
+		{ID: 1, RawUpQuery: "CREATE TABLE users (id SERIAL PRIMARY KEY, email TEXT NOT NULL);", RawDownQuery: "DROP TABLE users;"},
+		{ID: 2, RawUpQuery: "CREATE UNIQUE INDEX users_email_idx ON users (email);", RawDownQuery: "DROP INDEX users_email_idx;"},
+		{
+			ID:           3,
+			RawUpQuery:   "-- pgutil:no-transaction\nREINDEX INDEX CONCURRENTLY users_email_idx;",
+			RawDownQuery: "-- pgutil:no-transaction\nVACUUM users;",
+		},
+		{ID: 4, RawUpQuery: "INSERT INTO users (email) VALUES ('test@gmail.com');", RawDownQuery: "DELETE FROM users;"},
+	}
+	reader := MigrationReaderFunc(func() ([]RawDefinition, error) { return definitions, nil })
+
+	db := NewTestDB(t)
+	ctx := context.Background()
+
+	runner, err := NewMigrationRunner(db, reader, log.NewNilLogger())
+	require.NoError(t, err)
+	require.NoError(t, runner.ApplyAll(ctx))
+
+	logs, err := runner.MigrationLogs(ctx)
+	require.NoError(t, err)
+	for _, id := range []int{1, 2, 3, 4} {
+		require.NotNil(t, logs[id].Success)
+		require.True(t, *logs[id].Success)
+	}
+
+	// Idempotent
+	require.NoError(t, runner.ApplyAll(ctx))
+
+	// Undo through a no-transaction down migration
+	require.NoError(t, runner.Undo(ctx, 1))
+	_, err = ScanInts(db.Query(ctx, RawQuery("SELECT id FROM users")))
+	require.ErrorContains(t, err, `relation "users" does not exist`)
+
+	t.Run("without directive", func(t *testing.T) {
+		definitions := []RawDefinition{
+			{ID: 1, RawUpQuery: "CREATE TABLE users (id SERIAL PRIMARY KEY);"},
+			{ID: 2, RawUpQuery: "VACUUM users;"},
+		}
+		reader := MigrationReaderFunc(func() ([]RawDefinition, error) { return definitions, nil })
+
+		runner, err := NewMigrationRunner(NewTestDB(t), reader, log.NewNilLogger())
+		require.NoError(t, err)
+		require.ErrorContains(t, runner.ApplyAll(ctx), "cannot run inside a transaction block")
+	})
+
+	t.Run("directive in one direction", func(t *testing.T) {
+		definitions := []RawDefinition{
+			{ID: 1, RawUpQuery: "CREATE TABLE users (id SERIAL PRIMARY KEY);", RawDownQuery: "DROP TABLE users;"},
+			{ID: 2, RawUpQuery: "-- pgutil:no-transaction\nVACUUM users;", RawDownQuery: "VACUUM users;"},
+		}
+		reader := MigrationReaderFunc(func() ([]RawDefinition, error) { return definitions, nil })
+
+		db := NewTestDB(t)
+		runner, err := NewMigrationRunner(db, reader, log.NewNilLogger())
+		require.NoError(t, err)
+		require.NoError(t, runner.ApplyAll(ctx))
+
+		plan, err := runner.Plan(ctx, UndoTarget(2))
+		require.NoError(t, err)
+		require.Len(t, plan, 1)
+		require.False(t, plan[0].NoTransaction)
+
+		// The directive in the up query does not apply to the down query
+		require.ErrorContains(t, runner.Undo(ctx, 1), "cannot run inside a transaction block")
+	})
+}
+
+func TestApplyParents(t *testing.T) {
+	definitions := []RawDefinition{
+		{ID: 1, RawUpQuery: "CREATE TABLE users (id SERIAL PRIMARY KEY);", RawDownQuery: "DROP TABLE users;"},
+		{ID: 2, Parents: []int{1}, RawUpQuery: "ALTER TABLE users ADD COLUMN email TEXT;", RawDownQuery: "ALTER TABLE users DROP COLUMN email;"},
+		{ID: 3, Parents: []int{1}, RawUpQuery: "ALTER TABLE users ADD COLUMN name TEXT;", RawDownQuery: "ALTER TABLE users DROP COLUMN name;"},
+		{ID: 4, Parents: []int{2, 3}, RawUpQuery: "INSERT INTO users (email, name) VALUES ('test@gmail.com', 'test');", RawDownQuery: "DELETE FROM users;"},
+	}
+	reader := MigrationReaderFunc(func() ([]RawDefinition, error) { return definitions, nil })
+
+	db := NewTestDB(t)
+	ctx := context.Background()
+
+	runner, err := NewMigrationRunner(db, reader, log.NewNilLogger())
+	require.NoError(t, err)
+
+	appliedIDs := func() (ids []int) {
+		logs, err := runner.MigrationLogs(ctx)
+		require.NoError(t, err)
+
+		for id, log := range logs {
+			if log.Success != nil && *log.Success && !log.Reverse {
+				ids = append(ids, id)
+			}
+		}
+		sort.Ints(ids)
+
+		return ids
+	}
+
+	// Applying a migration applies only its ancestors
+	require.NoError(t, runner.Apply(ctx, 3))
+	require.Equal(t, []int{1, 3}, appliedIDs())
+
+	require.NoError(t, runner.ApplyAll(ctx))
+	require.Equal(t, []int{1, 2, 3, 4}, appliedIDs())
+
+	// Undoing a migration undoes only its descendants
+	require.NoError(t, runner.Undo(ctx, 2))
+	require.Equal(t, []int{1, 3}, appliedIDs())
+
+	_, _, err = ScanNilString(db.Query(ctx, RawQuery("SELECT name FROM users")))
+	require.NoError(t, err)
+	_, _, err = ScanNilString(db.Query(ctx, RawQuery("SELECT email FROM users")))
+	require.ErrorContains(t, err, `column "email" does not exist`)
+}
+
+func TestOutOfOrderMigrations(t *testing.T) {
+	definitions := []RawDefinition{
+		{ID: 20240101000000, RawUpQuery: "CREATE TABLE users (id SERIAL PRIMARY KEY);"},
+		{ID: 20240102000000, RawUpQuery: "ALTER TABLE users ADD COLUMN email TEXT;"},
+		{ID: 20240103000000, RawUpQuery: "ALTER TABLE users ADD COLUMN name TEXT;"},
+	}
+	reader := MigrationReaderFunc(func() ([]RawDefinition, error) { return definitions, nil })
+	readerWithoutSecond := MigrationReaderFunc(func() ([]RawDefinition, error) {
+		return []RawDefinition{definitions[0], definitions[2]}, nil
+	})
+
+	db := NewTestDB(t)
+	ctx := context.Background()
+
+	// Validation does not create the migration logs table
+	runner, err := NewMigrationRunner(db, readerWithoutSecond, log.NewNilLogger())
+	require.NoError(t, err)
+	outOfOrder, err := runner.OutOfOrderMigrations(ctx)
+	require.NoError(t, err)
+	require.Empty(t, outOfOrder)
+	modified, err := runner.ModifiedMigrations(ctx)
+	require.NoError(t, err)
+	require.Empty(t, modified)
+	logsTableExists, _, err := ScanBool(db.Query(ctx, RawQuery("SELECT to_regclass('migration_logs') IS NOT NULL")))
+	require.NoError(t, err)
+	require.False(t, logsTableExists)
+
+	// Apply migrations from a branch without the second migration
+	require.NoError(t, runner.ApplyAll(ctx))
+
+	outOfOrder, err = runner.OutOfOrderMigrations(ctx)
+	require.NoError(t, err)
+	require.Empty(t, outOfOrder)
+
+	// Merge in the second migration
+	runner, err = NewMigrationRunner(db, reader, log.NewNilLogger())
+	require.NoError(t, err)
+
+	outOfOrder, err = runner.OutOfOrderMigrations(ctx)
+	require.NoError(t, err)
+	require.Len(t, outOfOrder, 1)
+	require.Equal(t, 20240102000000, outOfOrder[0].ID)
+
+	require.NoError(t, runner.ApplyAll(ctx))
+	outOfOrder, err = runner.OutOfOrderMigrations(ctx)
+	require.NoError(t, err)
+	require.Empty(t, outOfOrder)
+}
+
+func TestModifiedMigrations(t *testing.T) {
+	definitions := []RawDefinition{
+		{ID: 1, RawUpQuery: "CREATE TABLE users (id SERIAL PRIMARY KEY);", RawDownQuery: "DROP TABLE users;"},
+		{ID: 2, RawUpQuery: "ALTER TABLE users ADD COLUMN email TEXT;", RawDownQuery: "ALTER TABLE users DROP COLUMN email;"},
+	}
+	modifiedDefinitions := []RawDefinition{
+		definitions[0],
+		{ID: 2, RawUpQuery: "ALTER TABLE users ADD COLUMN email TEXT NOT NULL;", RawDownQuery: "ALTER TABLE users DROP COLUMN email;"},
+		{ID: 3, RawUpQuery: "ALTER TABLE users ADD COLUMN name TEXT;", RawDownQuery: "ALTER TABLE users DROP COLUMN name;"},
+	}
+	reader := MigrationReaderFunc(func() ([]RawDefinition, error) { return definitions, nil })
+	modifiedReader := MigrationReaderFunc(func() ([]RawDefinition, error) { return modifiedDefinitions, nil })
+
+	setup := func(t *testing.T) DB {
+		db := NewTestDB(t)
+		ctx := context.Background()
+
+		runner, err := NewMigrationRunner(db, reader, log.NewNilLogger())
+		require.NoError(t, err)
+		require.NoError(t, runner.ApplyAll(ctx))
+
+		logs, err := runner.MigrationLogs(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, logs[2].Checksum)
+		require.Equal(t, runner.Definitions()[1].Checksum(), *logs[2].Checksum)
+
+		return db
+	}
+
+	t.Run("refuse", func(t *testing.T) {
+		db := setup(t)
+		ctx := context.Background()
+
+		runner, err := NewMigrationRunner(db, modifiedReader, log.NewNilLogger())
+		require.NoError(t, err)
+
+		modified, err := runner.ModifiedMigrations(ctx)
+		require.NoError(t, err)
+		require.Len(t, modified, 1)
+		require.Equal(t, 2, modified[0].ID)
+
+		var mismatchErr *ChecksumMismatchError
+		require.ErrorAs(t, runner.ApplyAll(ctx), &mismatchErr)
+		require.Equal(t, []int{2}, mismatchErr.MigrationIDs)
+
+		// Migration 3 must not have been applied
+		_, _, err = ScanNilString(db.Query(ctx, RawQuery("SELECT name FROM users")))
+		require.ErrorContains(t, err, `column "name" does not exist`)
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		db := setup(t)
+		ctx := context.Background()
+
+		runner, err := NewMigrationRunner(db, modifiedReader, log.NewNilLogger(), WithRunnerChecksumMismatchPolicy(ChecksumMismatchWarn))
+		require.NoError(t, err)
+		require.NoError(t, runner.ApplyAll(ctx))
+
+		_, _, err = ScanNilString(db.Query(ctx, RawQuery("SELECT name FROM users")))
+		require.NoError(t, err)
+	})
+
+	t.Run("reapplied", func(t *testing.T) {
+		db := setup(t)
+		ctx := context.Background()
+
+		// Undoing and reapplying the modified migration records its new checksum
+		runner, err := NewMigrationRunner(db, modifiedReader, log.NewNilLogger(), WithRunnerChecksumMismatchPolicy(ChecksumMismatchIgnore))
+		require.NoError(t, err)
+		require.NoError(t, runner.Undo(ctx, 2))
+
+		runner, err = NewMigrationRunner(db, modifiedReader, log.NewNilLogger())
+		require.NoError(t, err)
+		require.NoError(t, runner.ApplyAll(ctx))
+
+		modified, err := runner.ModifiedMigrations(ctx)
+		require.NoError(t, err)
+		require.Empty(t, modified)
+	})
+}
+
+func TestPlan(t *testing.T) {
+	definitions := []RawDefinition{
+		{ID: 1, RawUpQuery: "CREATE TABLE users (id SERIAL PRIMARY KEY, email TEXT);", RawDownQuery: "DROP TABLE users;"},
+		{ID: 2, RawUpQuery: "CREATE INDEX CONCURRENTLY users_email_idx ON users (email);", RawDownQuery: "DROP INDEX users_email_idx;"},
+		{ID: 3, RawUpQuery: "ALTER TABLE users ADD COLUMN name TEXT;", RawDownQuery: "ALTER TABLE users DROP COLUMN name;"},
+	}
+	reader := MigrationReaderFunc(func() ([]RawDefinition, error) { return definitions, nil })
+
+	db := NewTestDB(t)
+	ctx := context.Background()
+
+	runner, err := NewMigrationRunner(db, reader, log.NewNilLogger())
+	require.NoError(t, err)
+
+	planIDs := func(target MigrationTarget) (ids []int, cic []bool) {
+		plan, err := runner.Plan(ctx, target)
+		require.NoError(t, err)
+
+		for _, migration := range plan {
+			ids = append(ids, migration.Definition.ID)
+			cic = append(cic, migration.ConcurrentIndexCreation)
+		}
+
+		return ids, cic
+	}
+
+	ids, cic := planIDs(ApplyAllTarget())
+	require.Equal(t, []int{1, 2, 3}, ids)
+	require.Equal(t, []bool{false, true, false}, cic)
+
+	ids, _ = planIDs(ApplyTarget(1))
+	require.Equal(t, []int{1}, ids)
+
+	ids, _ = planIDs(UndoTarget(1))
+	require.Empty(t, ids)
+
+	// Planning does not apply migrations or create the migration logs table
+	_, _, err = ScanNilString(db.Query(ctx, RawQuery("SELECT email FROM users")))
+	require.ErrorContains(t, err, `relation "users" does not exist`)
+	logsTableExists, _, err := ScanBool(db.Query(ctx, RawQuery("SELECT to_regclass('migration_logs') IS NOT NULL")))
+	require.NoError(t, err)
+	require.False(t, logsTableExists)
+
+	require.NoError(t, runner.Apply(ctx, 2))
+
+	ids, _ = planIDs(ApplyAllTarget())
+	require.Equal(t, []int{3}, ids)
+
+	ids, cic = planIDs(UndoTarget(1))
+	require.Equal(t, []int{2, 1}, ids)
+	require.Equal(t, []bool{false, false}, cic)
+
+	// Logs tables written by earlier versions are read without being altered
+	require.NoError(t, db.Exec(ctx, RawQuery("ALTER TABLE migration_logs DROP COLUMN checksum")))
+	ids, _ = planIDs(ApplyAllTarget())
+	require.Equal(t, []int{3}, ids)
+
+	plan, err := runner.Plan(ctx, UndoTarget(2))
+	require.NoError(t, err)
+	require.Len(t, plan, 1)
+	require.True(t, plan[0].Reverse)
+	require.Equal(t, RawQuery("DROP INDEX users_email_idx;"), plan[0].Query)
+
+	_, err = runner.Plan(ctx, ApplyTarget(4))
+	require.ErrorContains(t, err, "migration not found")
+}
+
+func TestSquashMigrations(t *testing.T) {
+	definitions := []RawDefinition{
+		{ID: 1, RawUpQuery: "CREATE TABLE users (id SERIAL PRIMARY KEY, email TEXT);", RawDownQuery: "DROP TABLE users;"},
+		{ID: 2, RawUpQuery: "CREATE INDEX CONCURRENTLY users_email_idx ON users (email);", RawDownQuery: "DROP INDEX users_email_idx;"},
+		{ID: 3, RawUpQuery: "ALTER TABLE users ADD COLUMN name TEXT NOT NULL DEFAULT '';", RawDownQuery: "ALTER TABLE users DROP COLUMN name;"},
+		{ID: 4, RawUpQuery: "ALTER TABLE users ADD COLUMN created_at TIMESTAMP WITH TIME ZONE;", RawDownQuery: "ALTER TABLE users DROP COLUMN created_at;"},
+	}
+	reader := MigrationReaderFunc(func() ([]RawDefinition, error) { return definitions, nil })
+	ctx := context.Background()
+
+	baseline, err := SquashMigrations(ctx, NewTestDB(t), reader, 3, log.NewNilLogger())
+	require.NoError(t, err)
+	require.Equal(t, 3, baseline.ID)
+	require.Equal(t, []int{1, 2, 3}, baseline.Squashes)
+	require.NotContains(t, baseline.RawUpQuery, "migration_logs")
+
+	squashedReader := MigrationReaderFunc(func() ([]RawDefinition, error) {
+		return []RawDefinition{baseline, definitions[3]}, nil
+	})
+
+	t.Run("empty database", func(t *testing.T) {
+		db := NewTestDB(t)
+
+		runner, err := NewMigrationRunner(db, squashedReader, log.NewNilLogger())
+		require.NoError(t, err)
+		require.NoError(t, runner.Apply(ctx, 3))
+
+		// The baseline recreates the schema of the squashed migrations
+		unsquashedDB := NewTestDB(t)
+		unsquashedRunner, err := NewMigrationRunner(unsquashedDB, reader, log.NewNilLogger())
+		require.NoError(t, err)
+		require.NoError(t, unsquashedRunner.Apply(ctx, 3))
+
+		schema, err := DescribeSchema(ctx, db)
+		require.NoError(t, err)
+		unsquashedSchema, err := DescribeSchema(ctx, unsquashedDB)
+		require.NoError(t, err)
+		require.Empty(t, Compare(withoutMigrationLogs(unsquashedSchema), withoutMigrationLogs(schema)))
+
+		require.NoError(t, runner.ApplyAll(ctx))
+		_, _, err = ScanNilString(db.Query(ctx, RawQuery("SELECT created_at::text FROM users")))
+		require.NoError(t, err)
+
+		// A baseline applied directly is checked for modifications
+		modifiedBaseline := baseline
+		modifiedBaseline.RawUpQuery += "\nSELECT 1;\n"
+		modifiedRunner, err := NewMigrationRunner(db, MigrationReaderFunc(func() ([]RawDefinition, error) {
+			return []RawDefinition{modifiedBaseline, definitions[3]}, nil
+		}), log.NewNilLogger())
+		require.NoError(t, err)
+		modified, err := modifiedRunner.ModifiedMigrations(ctx)
+		require.NoError(t, err)
+		require.Len(t, modified, 1)
+		require.Equal(t, 3, modified[0].ID)
+
+		// Undoing the baseline drops the squashed schema
+		require.NoError(t, runner.Undo(ctx, 3))
+		_, _, err = ScanNilString(db.Query(ctx, RawQuery("SELECT name FROM users")))
+		require.ErrorContains(t, err, `relation "users" does not exist`)
+	})
+
+	t.Run("already applied", func(t *testing.T) {
+		db := NewTestDB(t)
+
+		runner, err := NewMigrationRunner(db, reader, log.NewNilLogger())
+		require.NoError(t, err)
+		require.NoError(t, runner.Apply(ctx, 3))
+
+		runner, err = NewMigrationRunner(db, squashedReader, log.NewNilLogger())
+		require.NoError(t, err)
+
+		plan, err := runner.Plan(ctx, ApplyAllTarget())
+		require.NoError(t, err)
+		require.Len(t, plan, 1)
+		require.Equal(t, 4, plan[0].Definition.ID)
+
+		// The log of the last squashed migration predates the baseline
+		modified, err := runner.ModifiedMigrations(ctx)
+		require.NoError(t, err)
+		require.Empty(t, modified)
+
+		require.NoError(t, runner.ApplyAll(ctx))
+	})
+
+	t.Run("partially applied", func(t *testing.T) {
+		db := NewTestDB(t)
+
+		runner, err := NewMigrationRunner(db, reader, log.NewNilLogger())
+		require.NoError(t, err)
+		require.NoError(t, runner.Apply(ctx, 2))
+
+		runner, err = NewMigrationRunner(db, squashedReader, log.NewNilLogger())
+		require.NoError(t, err)
+		require.ErrorContains(t, runner.ApplyAll(ctx), "cannot apply baseline migration 3")
+	})
+
+	t.Run("non-ancestor", func(t *testing.T) {
+		reader := MigrationReaderFunc(func() ([]RawDefinition, error) {
+			return []RawDefinition{definitions[0], {ID: 2, RawUpQuery: "SELECT 1;"}, {ID: 3, RawUpQuery: "SELECT 1;", Parents: []int{1}}}, nil
+		})
+
+		_, err := SquashMigrations(ctx, NewTestDB(t), reader, 3, log.NewNilLogger())
+		require.ErrorContains(t, err, "migration 2 precedes migration 3 but is not one of its ancestors")
+	})
+}
+
+func TestApplyGoMigrations(t *testing.T) {
+	sqlReader := MigrationReaderFunc(func() ([]RawDefinition, error) {
+		return []RawDefinition{
+			{ID: 1, RawUpQuery: "CREATE TABLE users (id SERIAL PRIMARY KEY, email TEXT NOT NULL);", RawDownQuery: "DROP TABLE users;"},
+			{ID: 2, RawUpQuery: "ALTER TABLE users ADD COLUMN domain TEXT;", RawDownQuery: "ALTER TABLE users DROP COLUMN domain;"},
+		}, nil
+	})
+
+	goReader := NewGoMigrationReader()
+	goReader.Register(3, "backfill domains",
+		func(ctx context.Context, tx DB) error {
+			require.True(t, tx.IsInTransaction())
+
+			emails, err := ScanStrings(tx.Query(ctx, RawQuery("SELECT email FROM users ORDER BY id")))
+			if err != nil {
+				return err
+			}
+
+			for _, email := range emails {
+				if err := tx.Exec(ctx, Query("UPDATE users SET domain = {:domain} WHERE email = {:email}", Args{
+					"domain": email[strings.LastIndex(email, "@")+1:],
+					"email":  email,
+				})); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		func(ctx context.Context, tx DB) error {
+			return tx.Exec(ctx, RawQuery("UPDATE users SET domain = NULL"))
+		},
+	)
+
+	db := NewTestDB(t)
+	ctx := context.Background()
+
+	runner, err := NewMigrationRunner(db, NewMultiMigrationReader(sqlReader, goReader), log.NewNilLogger())
+	require.NoError(t, err)
+	require.NoError(t, runner.Apply(ctx, 2))
+	require.NoError(t, db.Exec(ctx, RawQuery("INSERT INTO users (email) VALUES ('a@example.com'), ('b@test.com')")))
+	require.NoError(t, runner.ApplyAll(ctx))
+
+	domains, err := ScanStrings(db.Query(ctx, RawQuery("SELECT domain FROM users ORDER BY id")))
+	require.NoError(t, err)
+	require.Equal(t, []string{"example.com", "test.com"}, domains)
+
+	logs, err := runner.MigrationLogs(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, logs[3].Success)
+	require.True(t, *logs[3].Success)
+	require.Nil(t, logs[3].Checksum)
+	require.NotNil(t, logs[2].Checksum)
+
+	require.NoError(t, runner.Undo(ctx, 3))
+	domains, err = ScanStrings(db.Query(ctx, RawQuery("SELECT COALESCE(domain, '') FROM users ORDER BY id")))
+	require.NoError(t, err)
+	require.Equal(t, []string{"", ""}, domains)
+
+	t.Run("failure", func(t *testing.T) {
+		goReader := NewGoMigrationReader()
+		goReader.Register(3, "failing backfill",
+			func(ctx context.Context, tx DB) error {
+				if err := tx.Exec(ctx, RawQuery("UPDATE users SET domain = 'partial'")); err != nil {
+					return err
+				}
+
+				return errors.New("backfill failed")
+			},
+			nil,
+		)
+
+		runner, err := NewMigrationRunner(db, NewMultiMigrationReader(sqlReader, goReader), log.NewNilLogger())
+		require.NoError(t, err)
+		require.ErrorContains(t, runner.ApplyAll(ctx), "backfill failed")
+
+		// Writes are rolled back with the migration's transaction
+		domains, err := ScanStrings(db.Query(ctx, RawQuery("SELECT COALESCE(domain, '') FROM users ORDER BY id")))
+		require.NoError(t, err)
+		require.Equal(t, []string{"", ""}, domains)
+
+		logs, err := runner.MigrationLogs(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, logs[3].Success)
+		require.False(t, *logs[3].Success)
+		require.Contains(t, *logs[3].ErrorMessage, "backfill failed")
+	})
+}
@@ -0,0 +1,96 @@
+package pgutil
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-nacelle/nacelle/v2"
+)
+
+// SquashMigrations applies the migration with the given identifier along with all of
+// its (transitive) parents to the given database, which should be empty, and returns
+// a baseline migration that recreates the resulting schema. The baseline takes the
+// given identifier and replaces all of the migrations that were applied, which must
+// then be removed from the migration source.
+//
+// The baseline is generated by comparing the described schema against an empty one,
+// so only the objects supported by DescribeSchema are recreated. Rows inserted by the
+// squashed migrations are not preserved.
+func SquashMigrations(ctx context.Context, db DB, reader MigrationReader, id int, logger nacelle.Logger) (RawDefinition, error) {
+	runner, err := NewMigrationRunner(db, reader, logger)
+	if err != nil {
+		return RawDefinition{}, err
+	}
+
+	definitions, err := runner.targetDefinitions(ApplyTarget(id))
+	if err != nil {
+		return RawDefinition{}, err
+	}
+
+	squashed := map[int]struct{}{}
+	for _, definition := range definitions {
+		squashed[definition.ID] = struct{}{}
+	}
+
+	var squashedIDs []int
+	for _, definition := range definitions {
+		squashedIDs = append(squashedIDs, definition.ID)
+		squashedIDs = append(squashedIDs, definition.Squashes...)
+	}
+	slices.Sort(squashedIDs)
+	squashedIDs = slices.Compact(squashedIDs)
+
+	for _, definition := range runner.definitions {
+		if _, ok := squashed[definition.ID]; ok {
+			continue
+		}
+
+		// The baseline has no parents of its own, so it must precede every migration
+		// that is not squashed into it
+		if definition.ID < id {
+			return RawDefinition{}, fmt.Errorf("migration %d precedes migration %d but is not one of its ancestors", definition.ID, id)
+		}
+
+		for _, parentID := range definition.Parents {
+			if _, ok := squashed[parentID]; ok && parentID != id {
+				return RawDefinition{}, fmt.Errorf("migration %d has parent %d, which would be squashed", definition.ID, parentID)
+			}
+		}
+	}
+
+	if err := runner.Apply(ctx, id); err != nil {
+		return RawDefinition{}, err
+	}
+
+	schema, err := DescribeSchema(ctx, db)
+	if err != nil {
+		return RawDefinition{}, err
+	}
+	schema = withoutMigrationLogs(schema)
+
+	return RawDefinition{
+		ID:           id,
+		Name:         "squashed baseline",
+		RawUpQuery:   strings.Join(Compare(schema, SchemaDescription{}), "\n\n") + "\n",
+		RawDownQuery: strings.Join(Compare(SchemaDescription{}, schema), "\n\n") + "\n",
+		Description:  fmt.Sprintf("Recreates the schema produced by migrations %d through %d.", squashedIDs[0], id),
+		Squashes:     squashedIDs,
+	}, nil
+}
+
+// withoutMigrationLogs removes the runner's bookkeeping table from the given schema.
+func withoutMigrationLogs(schema SchemaDescription) SchemaDescription {
+	schema.Tables = slices.DeleteFunc(slices.Clone(schema.Tables), func(d TableDescription) bool {
+		return d.Name == "migration_logs"
+	})
+	schema.Sequences = slices.DeleteFunc(slices.Clone(schema.Sequences), func(d SequenceDescription) bool {
+		return d.Name == "migration_logs_id_seq"
+	})
+	schema.ColumnDependencies = slices.DeleteFunc(slices.Clone(schema.ColumnDependencies), func(d ColumnDependency) bool {
+		return d.UsedTableOrView == "migration_logs"
+	})
+
+	return schema
+}
@@ -0,0 +1,281 @@
+package pgutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-nacelle/nacelle/v2"
+	"github.com/lib/pq"
+	"github.com/segmentio/fasthash/fnv1"
+)
+
+// Outbox records events in the pgutil_outbox_events table (see NewOutboxMigrationReader)
+// in the same transaction as the state changes they describe. An OutboxRelay delivers
+// recorded events to a Publisher. Events are partitioned by key; events within the
+// same partition are delivered in the order in which their transactions committed.
+type Outbox struct {
+	name          string
+	locker        *TransactionalLocker
+	numPartitions int
+	batchSize     int
+	pollInterval  time.Duration
+	listenerURL   string
+}
+
+type OutboxEvent struct {
+	ID        int64
+	Partition int
+	Topic     string
+	Key       string
+	Payload   json.RawMessage
+	CreatedAt time.Time
+}
+
+// Publisher delivers outbox events to an external system. Events that were passed
+// to a call that returned an error are delivered again later, so delivery is
+// at-least-once.
+type Publisher interface {
+	Publish(ctx context.Context, events []OutboxEvent) error
+}
+
+type PublisherFunc func(ctx context.Context, events []OutboxEvent) error
+
+func (f PublisherFunc) Publish(ctx context.Context, events []OutboxEvent) error {
+	return f(ctx, events)
+}
+
+const outboxNotifyChannel = "pgutil_outbox"
+
+func NewOutbox(db DB, name string, configs ...OutboxConfigFunc) (*Outbox, error) {
+	options := getOutboxOptions(configs)
+	if options.numPartitions < 1 {
+		return nil, fmt.Errorf("outbox partitions must be positive, got %d", options.numPartitions)
+	}
+	if options.batchSize < 1 {
+		return nil, fmt.Errorf("outbox batch size must be positive, got %d", options.batchSize)
+	}
+
+	locker, err := NewTransactionalLocker(db, StringKey("nacelle/pgutil.outbox"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Outbox{
+		name:          name,
+		locker:        locker,
+		numPartitions: options.numPartitions,
+		batchSize:     options.batchSize,
+		pollInterval:  options.pollInterval,
+		listenerURL:   options.listenerURL,
+	}, nil
+}
+
+// Write records an event with the given JSON-encodable payload. The given database
+// handle should be the transaction in which the corresponding state change is made.
+//
+// Writers of the same partition are serialized: the transaction holds the partition's
+// lock until it commits or rolls back, so that events are numbered in commit order.
+// Transactions that write events to several partitions should write them in a
+// consistent order (e.g., sorted by key) to avoid deadlocks.
+func (o *Outbox) Write(ctx context.Context, tx DB, topic, key string, payload any) error {
+	serialized, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: The lock is taken in the same statement that assigns the event's identifier
+	// so that it is held until commit even if the given handle is not a transaction.
+	partition := o.partition(key)
+	if err := tx.Exec(ctx, Query(`
+		INSERT INTO pgutil_outbox_events (outbox, partition, topic, key, payload)
+		SELECT {:outbox}::text, {:partition}::integer, {:topic}::text, {:key}::text, {:payload}::jsonb
+		FROM pg_advisory_xact_lock({:lock_key}::bigint)
+	`, Args{
+		"outbox":    o.name,
+		"partition": partition,
+		"topic":     topic,
+		"key":       key,
+		"payload":   string(serialized),
+		"lock_key":  o.writeLockKey(partition),
+	})); err != nil {
+		return err
+	}
+
+	// Relays are woken when the caller's transaction commits; a rollback discards
+	// the notification along with the event
+	return tx.Exec(ctx, Query("SELECT pg_notify({:channel}, {:outbox})", Args{
+		"channel": outboxNotifyChannel,
+		"outbox":  o.name,
+	}))
+}
+
+func (o *Outbox) partition(key string) int {
+	return int(fnv1.HashString32(key) % uint32(o.numPartitions))
+}
+
+// writeLockKey returns the key of the lock held by transactions writing events to the
+// given partition.
+func (o *Outbox) writeLockKey(partition int) int64 {
+	return StringKey64(fmt.Sprintf("nacelle/pgutil.outbox/%s/%d/write", o.name, partition))
+}
+
+// relayLockKey returns the key of the lock held while relaying the given partition.
+func (o *Outbox) relayLockKey(partition int) int64 {
+	return StringKey64(fmt.Sprintf("nacelle/pgutil.outbox/%s/%d/relay", o.name, partition))
+}
+
+// RelayOnce delivers up to one batch of unpublished events from each partition that
+// is not concurrently being relayed elsewhere. It returns the number of events
+// published. A failure to relay one partition does not prevent relaying the others.
+func (o *Outbox) RelayOnce(ctx context.Context, publisher Publisher) (published int, _ error) {
+	var errs []error
+	for partition := 0; partition < o.numPartitions; partition++ {
+		n, err := o.relayPartition(ctx, publisher, partition)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to relay partition %d: %w", partition, err))
+		}
+
+		published += n
+	}
+
+	return published, errors.Join(errs...)
+}
+
+var scanOutboxEvents = NewSliceScanner(func(s Scanner) (e OutboxEvent, _ error) {
+	var payload []byte
+	err := s.Scan(&e.ID, &e.Partition, &e.Topic, &e.Key, &payload, &e.CreatedAt)
+	e.Payload = payload
+	return e, err
+})
+
+func (o *Outbox) relayPartition(ctx context.Context, publisher Publisher, partition int) (published int, _ error) {
+	// Holding the partition's lock for the duration of the transaction ensures that
+	// at most one relay delivers the partition's events at a time, preserving order.
+	// Events are ordered by identifier, which follows commit order as writers of the
+	// same partition are serialized (see Write).
+	_, err := o.locker.TryWithLock64(ctx, o.relayLockKey(partition), func(tx DB) error {
+		events, err := scanOutboxEvents(tx.Query(ctx, Query(`
+			SELECT id, partition, topic, key, payload, created_at
+			FROM pgutil_outbox_events
+			WHERE outbox = {:outbox} AND partition = {:partition} AND published_at IS NULL
+			ORDER BY id
+			LIMIT {:batch_size}
+		`, Args{
+			"outbox":     o.name,
+			"partition":  partition,
+			"batch_size": o.batchSize,
+		})))
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		if err := publisher.Publish(ctx, events); err != nil {
+			return err
+		}
+
+		ids := make([]int64, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+
+		if err := tx.Exec(ctx, Query(`
+			UPDATE pgutil_outbox_events
+			SET published_at = current_timestamp
+			WHERE id = ANY({:ids})
+		`, Args{
+			"ids": pq.Array(ids),
+		})); err != nil {
+			return err
+		}
+
+		published = len(events)
+		return nil
+	})
+
+	return published, err
+}
+
+// OutboxRelay continuously delivers events from an outbox to a publisher until it
+// is stopped (see Run and Stop). Multiple relays of the same outbox may run
+// concurrently; each partition is relayed by at most one of them at a time.
+type OutboxRelay struct {
+	Logger nacelle.Logger `service:"logger"`
+
+	outbox    *Outbox
+	publisher Publisher
+	halt      chan struct{}
+	haltOnce  sync.Once
+}
+
+func NewOutboxRelay(outbox *Outbox, publisher Publisher) *OutboxRelay {
+	return &OutboxRelay{
+		outbox:    outbox,
+		publisher: publisher,
+		halt:      make(chan struct{}),
+	}
+}
+
+// Run relays events until the given context is canceled or Stop is called. Failures
+// to publish are logged and retried on the next poll.
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-r.halt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var wakeup <-chan struct{}
+	if r.outbox.listenerURL != "" {
+		ch, closeListener, err := listenForWakeups(ctx, r.outbox.listenerURL, outboxNotifyChannel, r.outbox.name)
+		if err != nil {
+			return err
+		}
+		defer closeListener()
+
+		wakeup = ch
+	}
+
+	for {
+		published, err := r.outbox.RelayOnce(ctx, r.publisher)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			r.logger().Error("Failed to relay outbox events (%s)", err)
+		}
+		if published > 0 && err == nil {
+			// Keep draining while there are events available
+			continue
+		}
+
+		select {
+		case <-time.After(r.outbox.pollInterval):
+		case <-wakeup:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Stop causes Run to return.
+func (r *OutboxRelay) Stop(ctx context.Context) error {
+	r.haltOnce.Do(func() { close(r.halt) })
+	return nil
+}
+
+func (r *OutboxRelay) logger() nacelle.Logger {
+	if r.Logger == nil {
+		return nacelle.NewNilLogger()
+	}
+
+	return r.Logger
+}
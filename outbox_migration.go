@@ -0,0 +1,13 @@
+package pgutil
+
+import "embed"
+
+//go:embed outbox_migration/*.sql
+var outboxMigrationFS embed.FS
+
+// NewOutboxMigrationReader returns a reader of a single migration, with the given
+// identifier, that creates the table backing Outbox. Combine it with the reader of
+// the application's own migrations via NewMultiMigrationReader.
+func NewOutboxMigrationReader(id int) MigrationReader {
+	return newEmbeddedMigrationReader(outboxMigrationFS, "outbox_migration", id, "create pgutil outbox events")
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pgutil_outbox_events;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS pgutil_outbox_events (
+    id bigserial PRIMARY KEY,
+    outbox text NOT NULL,
+    partition integer NOT NULL,
+    topic text NOT NULL,
+    key text NOT NULL,
+    payload jsonb NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT current_timestamp,
+    published_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS pgutil_outbox_events_unpublished ON pgutil_outbox_events (outbox, partition, id) WHERE published_at IS NULL;
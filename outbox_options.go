@@ -0,0 +1,64 @@
+package pgutil
+
+import "time"
+
+type (
+	outboxOptions struct {
+		numPartitions int
+		batchSize     int
+		pollInterval  time.Duration
+		listenerURL   string
+	}
+
+	// OutboxConfigFunc is a function used to configure an outbox.
+	OutboxConfigFunc func(*outboxOptions)
+)
+
+func getOutboxOptions(configs []OutboxConfigFunc) *outboxOptions {
+	options := &outboxOptions{
+		numPartitions: 1,
+		batchSize:     100,
+		pollInterval:  time.Second,
+	}
+
+	for _, f := range configs {
+		f(options)
+	}
+
+	return options
+}
+
+// WithOutboxPartitions sets the number of partitions over which events are spread
+// by key. Partitions are relayed independently, so a larger number allows multiple
+// relays to make progress concurrently. Changing the number of partitions while
+// unpublished events exist may reorder events sharing a key.
+func WithOutboxPartitions(numPartitions int) OutboxConfigFunc {
+	return func(o *outboxOptions) {
+		o.numPartitions = numPartitions
+	}
+}
+
+// WithOutboxBatchSize sets the maximum number of events passed to a single call to
+// Publish.
+func WithOutboxBatchSize(batchSize int) OutboxConfigFunc {
+	return func(o *outboxOptions) {
+		o.batchSize = batchSize
+	}
+}
+
+// WithOutboxPollInterval sets how long an idle relay waits before checking for new events.
+func WithOutboxPollInterval(interval time.Duration) OutboxConfigFunc {
+	return func(o *outboxOptions) {
+		o.pollInterval = interval
+	}
+}
+
+// WithOutboxListener wakes idle relays as soon as events are written via LISTEN/NOTIFY
+// on a dedicated connection to the given database. Relays still poll at the poll
+// interval so that events written while the listener was reconnecting are not left
+// waiting for the next write to the same outbox.
+func WithOutboxListener(databaseURL string) OutboxConfigFunc {
+	return func(o *outboxOptions) {
+		o.listenerURL = databaseURL
+	}
+}
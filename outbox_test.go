@@ -0,0 +1,194 @@
+package pgutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-nacelle/log/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, configs ...OutboxConfigFunc) (DB, *Outbox) {
+		db := NewTestDB(t)
+		runner, err := NewMigrationRunner(db, NewOutboxMigrationReader(1), log.NewNilLogger())
+		require.NoError(t, err)
+		require.NoError(t, runner.ApplyAll(ctx))
+
+		outbox, err := NewOutbox(db, "test", configs...)
+		require.NoError(t, err)
+		return db, outbox
+	}
+
+	type recordingPublisher struct {
+		sync.Mutex
+		keys []string
+	}
+	record := func(p *recordingPublisher) PublisherFunc {
+		return func(ctx context.Context, events []OutboxEvent) error {
+			p.Lock()
+			defer p.Unlock()
+
+			for _, event := range events {
+				var payload string
+				if err := json.Unmarshal(event.Payload, &payload); err != nil {
+					return err
+				}
+
+				p.keys = append(p.keys, fmt.Sprintf("%s/%s/%s", event.Topic, event.Key, payload))
+			}
+
+			return nil
+		}
+	}
+
+	t.Run("relay", func(t *testing.T) {
+		db, outbox := setup(t, WithOutboxBatchSize(2))
+
+		errRollback := errors.New("rollback")
+		require.ErrorIs(t, db.WithTransaction(ctx, func(tx DB) error {
+			require.NoError(t, outbox.Write(ctx, tx, "users", "1", "discarded"))
+			return errRollback
+		}), errRollback)
+
+		require.NoError(t, db.WithTransaction(ctx, func(tx DB) error {
+			for _, payload := range []string{"a", "b", "c"} {
+				if err := outbox.Write(ctx, tx, "users", "1", payload); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}))
+
+		publisher := &recordingPublisher{}
+		published, err := outbox.RelayOnce(ctx, record(publisher))
+		require.NoError(t, err)
+		assert.Equal(t, 2, published)
+
+		published, err = outbox.RelayOnce(ctx, record(publisher))
+		require.NoError(t, err)
+		assert.Equal(t, 1, published)
+
+		published, err = outbox.RelayOnce(ctx, record(publisher))
+		require.NoError(t, err)
+		assert.Equal(t, 0, published)
+
+		assert.Equal(t, []string{"users/1/a", "users/1/b", "users/1/c"}, publisher.keys)
+	})
+
+	t.Run("publish failure", func(t *testing.T) {
+		db, outbox := setup(t)
+		require.NoError(t, outbox.Write(ctx, db, "users", "1", "a"))
+
+		errPublish := errors.New("broker unavailable")
+		_, err := outbox.RelayOnce(ctx, PublisherFunc(func(ctx context.Context, events []OutboxEvent) error {
+			return errPublish
+		}))
+		require.ErrorIs(t, err, errPublish)
+
+		// Redelivered on the next attempt
+		publisher := &recordingPublisher{}
+		published, err := outbox.RelayOnce(ctx, record(publisher))
+		require.NoError(t, err)
+		assert.Equal(t, 1, published)
+		assert.Equal(t, []string{"users/1/a"}, publisher.keys)
+	})
+
+	t.Run("one relay per partition", func(t *testing.T) {
+		db, outbox := setup(t, WithOutboxPartitions(4))
+		for i := 0; i < 20; i++ {
+			require.NoError(t, outbox.Write(ctx, db, "users", fmt.Sprintf("%d", i), "a"))
+		}
+
+		partition := outbox.partition("0")
+		require.NoError(t, outbox.locker.WithLock64(ctx, outbox.relayLockKey(partition), func(tx DB) error {
+			published, err := outbox.RelayOnce(ctx, PublisherFunc(func(ctx context.Context, events []OutboxEvent) error {
+				for _, event := range events {
+					assert.NotEqual(t, partition, event.Partition)
+				}
+
+				return nil
+			}))
+			require.NoError(t, err)
+			assert.Less(t, published, 20)
+			return nil
+		}))
+
+		// Remaining partition is relayed once released
+		published, err := outbox.RelayOnce(ctx, PublisherFunc(func(ctx context.Context, events []OutboxEvent) error {
+			for _, event := range events {
+				assert.Equal(t, partition, event.Partition)
+			}
+
+			return nil
+		}))
+		require.NoError(t, err)
+		assert.Greater(t, published, 0)
+	})
+
+	t.Run("commit order", func(t *testing.T) {
+		db, outbox := setup(t)
+
+		first, err := db.Transact(ctx)
+		require.NoError(t, err)
+		require.NoError(t, outbox.Write(ctx, first, "users", "1", "first"))
+
+		// A concurrent writer of the same partition waits for the first to commit
+		written := make(chan error, 1)
+		go func() {
+			written <- db.WithTransaction(ctx, func(tx DB) error {
+				return outbox.Write(ctx, tx, "users", "1", "second")
+			})
+		}()
+
+		select {
+		case err := <-written:
+			t.Fatalf("unexpected write before commit: %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		require.NoError(t, first.Done(nil))
+		require.NoError(t, <-written)
+
+		publisher := &recordingPublisher{}
+		_, err = outbox.RelayOnce(ctx, record(publisher))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"users/1/first", "users/1/second"}, publisher.keys)
+	})
+
+	t.Run("relay process", func(t *testing.T) {
+		db, outbox := setup(t, WithOutboxPollInterval(10*time.Millisecond))
+		publisher := &recordingPublisher{}
+		relay := NewOutboxRelay(outbox, record(publisher))
+
+		errs := make(chan error, 1)
+		go func() { errs <- relay.Run(ctx) }()
+
+		require.NoError(t, outbox.Write(ctx, db, "users", "1", "a"))
+		require.Eventually(t, func() bool {
+			publisher.Lock()
+			defer publisher.Unlock()
+			return len(publisher.keys) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		require.NoError(t, relay.Stop(ctx))
+		require.NoError(t, <-errs)
+	})
+}
+
+func TestNewOutboxInvalidOptions(t *testing.T) {
+	_, err := NewOutbox(nil, "events", WithOutboxPartitions(0))
+	require.ErrorContains(t, err, "outbox partitions must be positive, got 0")
+
+	_, err = NewOutbox(nil, "events", WithOutboxBatchSize(-1))
+	require.ErrorContains(t, err, "outbox batch size must be positive, got -1")
+}
@@ -3,6 +3,7 @@ package pgutil
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -11,17 +12,75 @@ type Q struct {
 	internalFormat    string
 	replacerPairs     []string
 	parameterizedArgs []any
+	prepared          bool
 }
 
 type Args map[string]any
 
+type QueryArgsError struct {
+	Format  string
+	Missing []string
+	Unused  []string
+}
+
+func (e *QueryArgsError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, fmt.Sprintf("no arg supplied for %s", quoteNames(e.Missing)))
+	}
+	if len(e.Unused) > 0 {
+		parts = append(parts, fmt.Sprintf("unused args %s", quoteNames(e.Unused)))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
 func Query(format string, args Args) Q {
+	q, err := TryQuery(format, args)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return q
+}
+
+// TryQuery is like Query but returns an error instead of panicking when a placeholder
+// has no corresponding arg.
+func TryQuery(format string, args Args) (Q, error) {
+	return tryQuery(format, args, false)
+}
+
+// TryQueryStrict is like TryQuery but also rejects args that are not referenced by any
+// placeholder. This is meant to be used in tests to catch typos in placeholder names.
+func TryQueryStrict(format string, args Args) (Q, error) {
+	return tryQuery(format, args, true)
+}
+
+func tryQuery(format string, args Args, strict bool) (Q, error) {
+	q, missing, unused := buildQuery(format, args)
+	if !strict {
+		unused = nil
+	}
+
+	if len(missing) > 0 || len(unused) > 0 {
+		return Q{}, &QueryArgsError{
+			Format:  format,
+			Missing: missing,
+			Unused:  unused,
+		}
+	}
+
+	return q, nil
+}
+
+func buildQuery(format string, args Args) (_ Q, missing, unused []string) {
 	var (
 		internalFormat      string
 		replacerPairs       []string
 		parameterizedArgs   []any
 		previousIndex       = 0
 		placeholdersToIndex = map[string]int{}
+		used                = map[string]struct{}{}
 	)
 
 	for _, part := range tokenize(format) {
@@ -34,8 +93,13 @@ func Query(format string, args Args) Q {
 
 		value, ok := args[name]
 		if !ok {
-			panic(fmt.Sprintf("no arg supplied for %q", name))
+			if _, ok := used[name]; !ok {
+				missing = append(missing, name)
+			}
+			used[name] = struct{}{}
+			continue
 		}
+		used[name] = struct{}{}
 
 		if q, ok := value.(Q); ok {
 			// Serialize all internal placeholders transforming `{$X}` -> `{${X+lastIndex}}`
@@ -46,8 +110,9 @@ func Query(format string, args Args) Q {
 			replacerPairs = append(replacerPairs, subReplacerPairs...)
 			parameterizedArgs = append(parameterizedArgs, subParameterizedArgs...)
 
-			// Bump indexes by number of placeholders in serialized query
-			previousIndex += len(subReplacerPairs) / 2
+			// Bump indexes by number of distinct parameters in serialized query (a reused
+			// placeholder occurs in several replacer pairs but binds a single parameter)
+			previousIndex += len(subParameterizedArgs)
 		} else {
 			// Re-use parameter if possible; otherwise create a new placeholder
 			index, ok := placeholdersToIndex[name]
@@ -67,11 +132,20 @@ func Query(format string, args Args) Q {
 		}
 	}
 
-	return Q{
+	for name := range args {
+		if _, ok := used[name]; !ok {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+
+	q := Q{
 		internalFormat:    internalFormat,
 		replacerPairs:     replacerPairs,
 		parameterizedArgs: parameterizedArgs,
 	}
+
+	return q, missing, unused
 }
 
 func Quote(format string) Q {
@@ -139,6 +213,15 @@ func extractPlaceholderName(part string) (string, bool) {
 	return "", false
 }
 
+func quoteNames(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, fmt.Sprintf("%q", name))
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
 func replaceWithPairs(format string, replacerPairs ...string) string {
 	return strings.NewReplacer(replacerPairs...).Replace(format)
 }
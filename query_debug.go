@@ -0,0 +1,144 @@
+package pgutil
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DebugString returns the query with all parameterized arguments inlined as
+// Postgres literals. The result is meant to be pasted into psql while debugging.
+//
+// WARNING: The output is NOT safe to execute programmatically. Literal escaping
+// is best-effort and the output must never be sent back to the database.
+func (q Q) DebugString() string {
+	return debugString(q.Format())
+}
+
+var debugPlaceholderPattern = regexp.MustCompile(`\$\d+`)
+
+func debugString(query string, args []any) string {
+	var sb strings.Builder
+	for _, segment := range splitSQL(query) {
+		if segment.kind != sqlSegmentCode {
+			// Leave placeholder-like text within literals and comments untouched
+			sb.WriteString(segment.text)
+			continue
+		}
+
+		sb.WriteString(debugPlaceholderPattern.ReplaceAllStringFunc(segment.text, func(placeholder string) string {
+			index, err := strconv.Atoi(placeholder[1:])
+			if err != nil || index < 1 || index > len(args) {
+				return placeholder
+			}
+
+			return debugLiteral(args[index-1])
+		}))
+	}
+
+	return sb.String()
+}
+
+func debugLiteral(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return debugQuoteLiteral(v)
+	case []byte:
+		if v == nil {
+			return "NULL"
+		}
+
+		return fmt.Sprintf(`'\x%s'::bytea`, hex.EncodeToString(v))
+	case bool:
+		if v {
+			return "TRUE"
+		}
+
+		return "FALSE"
+	case time.Time:
+		return fmt.Sprintf("%s::timestamptz", debugQuoteLiteral(v.Format(time.RFC3339Nano)))
+	case float32:
+		return debugFloatLiteral(float64(v), 32)
+	case float64:
+		return debugFloatLiteral(v, 64)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v)
+	case driver.Valuer:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return "NULL"
+		}
+
+		inner, err := v.Value()
+		if err != nil {
+			return fmt.Sprintf("/* %s */ NULL", strings.ReplaceAll(err.Error(), "*/", "* /"))
+		}
+
+		return debugLiteral(inner)
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return "NULL"
+		}
+
+		return debugLiteral(rv.Elem().Interface())
+
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return "NULL"
+		}
+		if rv.Len() == 0 {
+			return "'{}'"
+		}
+
+		elements := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			elements = append(elements, debugLiteral(rv.Index(i).Interface()))
+		}
+
+		return fmt.Sprintf("ARRAY[%s]", strings.Join(elements, ", "))
+
+	case reflect.String:
+		return debugQuoteLiteral(rv.String())
+	case reflect.Bool:
+		return debugLiteral(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return debugFloatLiteral(rv.Float(), 64)
+	}
+
+	return debugQuoteLiteral(fmt.Sprintf("%v", value))
+}
+
+func debugFloatLiteral(v float64, bitSize int) string {
+	switch {
+	case math.IsNaN(v):
+		return "'NaN'::float8"
+	case math.IsInf(v, 1):
+		return "'Infinity'::float8"
+	case math.IsInf(v, -1):
+		return "'-Infinity'::float8"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, bitSize)
+}
+
+func debugQuoteLiteral(literal string) string {
+	// NOTE: pq prefixes escaped literals with a space (e.g., ` E'a\\b'`)
+	return strings.TrimPrefix(pq.QuoteLiteral(literal), " ")
+}
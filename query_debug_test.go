@@ -0,0 +1,73 @@
+package pgutil
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugString(t *testing.T) {
+	var (
+		name    = "efritz"
+		nilName *string
+	)
+
+	for _, testCase := range []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{"nil", nil, "NULL"},
+		{"string", "o'reilly", "'o''reilly'"},
+		{"string with backslash", `a\b`, `E'a\\b'`},
+		{"int", 42, "42"},
+		{"negative int", int64(-7), "-7"},
+		{"uint", uint8(200), "200"},
+		{"float", 3.25, "3.25"},
+		{"nan", math.NaN(), "'NaN'::float8"},
+		{"bool", true, "TRUE"},
+		{"bytes", []byte{0xde, 0xad, 0xbe, 0xef}, `'\xdeadbeef'::bytea`},
+		{"time", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), "'2024-01-02T03:04:05Z'::timestamptz"},
+		{"pointer", &name, "'efritz'"},
+		{"nil pointer", nilName, "NULL"},
+		{"slice", []int{1, 2, 3}, "ARRAY[1, 2, 3]"},
+		{"empty slice", []string{}, "'{}'"},
+		{"array", [2]string{"a", "b"}, "ARRAY['a', 'b']"},
+		{"valuer", pq.Array([]string{"a", "b"}), `'{"a","b"}'`},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			q := Query("SELECT {:value}", Args{"value": testCase.value})
+			assert.Equal(t, "SELECT "+testCase.expected, q.DebugString())
+		})
+	}
+
+	t.Run("nested", func(t *testing.T) {
+		cond := Query("name = {:name} AND age > {:age}", Args{"name": "efritz", "age": 18})
+		q := Query("SELECT * FROM users WHERE {:cond} LIMIT {:limit}", Args{"cond": cond, "limit": 10})
+		assert.Equal(t, "SELECT * FROM users WHERE name = 'efritz' AND age > 18 LIMIT 10", q.DebugString())
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		q := RawQuery("INSERT INTO t VALUES ($00001,$00002),($00003,$00004)", 1, "a", 2, nil)
+		assert.Equal(t, "INSERT INTO t VALUES (1,'a'),(2,NULL)", q.DebugString())
+	})
+
+	t.Run("quoted placeholders", func(t *testing.T) {
+		q := RawQuery(strings.Join([]string{
+			`SELECT $1, '$1', E'\'$1', "$1"`,
+			`-- $1`,
+			`/* $1 /* $1 */ $1 */`,
+			`$$ $1 $$, $fn$ $1 $fn$, $2`,
+		}, "\n"), 42, "a")
+		assert.Equal(t, strings.Join([]string{
+			`SELECT 42, '$1', E'\'$1', "$1"`,
+			`-- $1`,
+			`/* $1 /* $1 */ $1 */`,
+			`$$ $1 $$, $fn$ $1 $fn$, 'a'`,
+		}, "\n"), q.DebugString())
+	})
+}
@@ -0,0 +1,97 @@
+package pgutil
+
+import "strings"
+
+// Join concatenates the given queries with the given separator. Empty queries
+// are skipped so that no dangling separators are emitted.
+func Join(sep string, qs ...Q) Q {
+	var (
+		internalFormat    string
+		replacerPairs     []string
+		parameterizedArgs []any
+		previousIndex     = 0
+		first             = true
+	)
+
+	for _, q := range qs {
+		if q.isEmpty() {
+			continue
+		}
+
+		if !first {
+			internalFormat += sep
+		}
+		first = false
+
+		// Serialize all internal placeholders transforming `{$X}` -> `{${X+lastIndex}}`
+		subInternalFormat, subReplacerPairs, subParameterizedArgs := q.bumpPlaceholderIndices(previousIndex)
+
+		// Embed this query into the internal format
+		internalFormat += subInternalFormat
+		replacerPairs = append(replacerPairs, subReplacerPairs...)
+		parameterizedArgs = append(parameterizedArgs, subParameterizedArgs...)
+
+		// Bump indexes by number of distinct parameters in serialized query (a reused
+		// placeholder occurs in several replacer pairs but binds a single parameter)
+		previousIndex += len(subParameterizedArgs)
+	}
+
+	return Q{
+		internalFormat:    internalFormat,
+		replacerPairs:     replacerPairs,
+		parameterizedArgs: parameterizedArgs,
+	}
+}
+
+// And joins the non-empty conditions with AND, parenthesizing each operand.
+func And(qs ...Q) Q {
+	return joinConditions(" AND ", qs)
+}
+
+// Or joins the non-empty conditions with OR, parenthesizing each operand.
+func Or(qs ...Q) Q {
+	return joinConditions(" OR ", qs)
+}
+
+// Where returns a WHERE clause over the conjunction of the given conditions,
+// or an empty query if all conditions are empty.
+func Where(qs ...Q) Q {
+	cond := And(qs...)
+	if cond.isEmpty() {
+		return Q{}
+	}
+
+	return Query("WHERE {:cond}", Args{"cond": cond})
+}
+
+// Optional returns the given query if cond is true and an empty query otherwise.
+func Optional(cond bool, q Q) Q {
+	if !cond {
+		return Q{}
+	}
+
+	return q
+}
+
+func joinConditions(sep string, qs []Q) Q {
+	nonEmpty := make([]Q, 0, len(qs))
+	for _, q := range qs {
+		if !q.isEmpty() {
+			nonEmpty = append(nonEmpty, q)
+		}
+	}
+
+	if len(nonEmpty) == 1 {
+		return nonEmpty[0]
+	}
+
+	for i, q := range nonEmpty {
+		nonEmpty[i] = Query("({:cond})", Args{"cond": q})
+	}
+
+	return Join(sep, nonEmpty...)
+}
+
+func (q Q) isEmpty() bool {
+	return strings.TrimSpace(q.internalFormat) == ""
+}
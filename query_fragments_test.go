@@ -0,0 +1,94 @@
+package pgutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryFragments(t *testing.T) {
+	testQuery := func(t *testing.T, q Q, expectedQuery string, expectedArgs ...any) {
+		t.Helper()
+
+		query, args := q.Format()
+		assert.Equal(t, expectedQuery, query)
+		assert.Equal(t, expectedArgs, args)
+	}
+
+	t.Run("join", func(t *testing.T) {
+		q := Join(", ",
+			Query("{:a}", Args{"a": 1}),
+			Q{},
+			Query("{:b}, {:a}", Args{"a": 2, "b": 3}),
+		)
+
+		testQuery(t, q, "$1, $2, $3", 1, 3, 2)
+	})
+
+	t.Run("reused placeholders", func(t *testing.T) {
+		q := And(
+			Query("id = {:id} OR parent_id = {:id}", Args{"id": 42}),
+			Query("name = {:name}", Args{"name": "efritz"}),
+		)
+		testQuery(t, q, "(id = $1 OR parent_id = $1) AND (name = $2)", 42, "efritz")
+
+		q = Query("SELECT * FROM t WHERE {:cond} LIMIT {:limit}", Args{
+			"cond":  Query("a = {:x} OR b = {:x}", Args{"x": 1}),
+			"limit": 10,
+		})
+		testQuery(t, q, "SELECT * FROM t WHERE a = $1 OR b = $1 LIMIT $2", 1, 10)
+	})
+
+	t.Run("join empty", func(t *testing.T) {
+		testQuery(t, Join(", "), "")
+		testQuery(t, Join(", ", Q{}, Quote("  ")), "")
+	})
+
+	t.Run("and", func(t *testing.T) {
+		q := And(
+			Query("name = {:name}", Args{"name": "efritz"}),
+			Optional(false, Query("age = {:age}", Args{"age": 34})),
+			Query("admin = {:admin}", Args{"admin": true}),
+		)
+
+		testQuery(t, q, "(name = $1) AND (admin = $2)", "efritz", true)
+	})
+
+	t.Run("single condition", func(t *testing.T) {
+		q := Or(Q{}, Query("name = {:name}", Args{"name": "efritz"}))
+		testQuery(t, q, "name = $1", "efritz")
+	})
+
+	t.Run("nested conditions", func(t *testing.T) {
+		q := And(
+			Query("age > {:age}", Args{"age": 18}),
+			Or(
+				Query("name = {:name}", Args{"name": "efritz"}),
+				Query("email = {:email}", Args{"email": "efritz@example.com"}),
+			),
+		)
+
+		testQuery(t, q, "(age > $1) AND ((name = $2) OR (email = $3))", 18, "efritz", "efritz@example.com")
+	})
+
+	t.Run("where", func(t *testing.T) {
+		q := Query("SELECT name FROM users {:where} LIMIT {:limit}", Args{
+			"where": Where(
+				Optional(true, Query("name = {:name}", Args{"name": "efritz"})),
+				Optional(false, Query("age = {:age}", Args{"age": 34})),
+			),
+			"limit": 10,
+		})
+
+		testQuery(t, q, "SELECT name FROM users WHERE name = $1 LIMIT $2", "efritz", 10)
+	})
+
+	t.Run("where empty", func(t *testing.T) {
+		q := Query("SELECT name FROM users {:where} LIMIT {:limit}", Args{
+			"where": Where(Optional(false, Query("age = {:age}", Args{"age": 34}))),
+			"limit": 10,
+		})
+
+		testQuery(t, q, "SELECT name FROM users  LIMIT $1", 10)
+	})
+}
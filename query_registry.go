@@ -0,0 +1,221 @@
+package pgutil
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type QueryRegistry struct {
+	queries map[string]RegisteredQuery
+}
+
+type RegisteredQuery struct {
+	Name         string
+	Filename     string
+	Format       string
+	Placeholders []string
+}
+
+func NewFilesystemQueryRegistry(dirname string) (*QueryRegistry, error) {
+	return newQueryRegistry(dirname, os.DirFS(dirname))
+}
+
+func NewQueryRegistry(fs fs.FS) (*QueryRegistry, error) {
+	return newQueryRegistry("<fs>", fs)
+}
+
+func newQueryRegistry(name string, fsys fs.FS) (*QueryRegistry, error) {
+	queries := map[string]RegisteredQuery{}
+
+	if err := fs.WalkDir(fsys, ".", func(filepath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("query directory %q does not exist", name)
+			}
+
+			return err
+		}
+
+		if entry.IsDir() || path.Ext(filepath) != ".sql" {
+			return nil
+		}
+
+		contents, err := readFile(fsys, filepath)
+		if err != nil {
+			return err
+		}
+
+		fileQueries, err := parseNamedQueries(filepath, string(contents))
+		if err != nil {
+			return err
+		}
+
+		for _, query := range fileQueries {
+			if existing, ok := queries[query.Name]; ok {
+				return fmt.Errorf("duplicate query name %q (defined in %s and %s)", query.Name, existing.Filename, query.Filename)
+			}
+
+			queries[query.Name] = query
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return &QueryRegistry{queries: queries}, nil
+}
+
+var queryNamePattern = regexp.MustCompile(`^\s*--\s*name:\s*(\S+)\s*$`)
+
+func parseNamedQueries(filename, contents string) (queries []RegisteredQuery, _ error) {
+	var (
+		name  string
+		lines []string
+	)
+
+	flush := func() error {
+		if name == "" {
+			if removeComments(strings.Join(lines, "\n")) != "" {
+				return fmt.Errorf("%s: query text outside of a named query", filename)
+			}
+
+			return nil
+		}
+
+		format := strings.TrimSpace(strings.Join(lines, "\n"))
+		if removeComments(format) == "" {
+			return fmt.Errorf("%s: query %q is empty", filename, name)
+		}
+
+		queries = append(queries, RegisteredQuery{
+			Name:         name,
+			Filename:     filename,
+			Format:       format,
+			Placeholders: extractPlaceholderNames(format),
+		})
+
+		return nil
+	}
+
+	for _, line := range strings.Split(contents, "\n") {
+		if matches := queryNamePattern.FindStringSubmatch(line); len(matches) > 0 {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+
+			name, lines = matches[1], nil
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return queries, nil
+}
+
+func extractPlaceholderNames(format string) []string {
+	var (
+		names = []string{}
+		seen  = map[string]struct{}{}
+	)
+
+	for _, matches := range placeholderPattern.FindAllStringSubmatch(format, -1) {
+		if _, ok := seen[matches[1]]; !ok {
+			seen[matches[1]] = struct{}{}
+			names = append(names, matches[1])
+		}
+	}
+
+	return names
+}
+
+func (r *QueryRegistry) Names() []string {
+	names := make([]string, 0, len(r.queries))
+	for name := range r.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (r *QueryRegistry) Get(name string) (RegisteredQuery, bool) {
+	query, ok := r.queries[name]
+	return query, ok
+}
+
+func (r *QueryRegistry) Query(name string, args Args) Q {
+	q, err := r.TryQuery(name, args)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return q
+}
+
+func (r *QueryRegistry) TryQuery(name string, args Args) (Q, error) {
+	query, ok := r.queries[name]
+	if !ok {
+		return Q{}, fmt.Errorf("unknown query %q", name)
+	}
+
+	return TryQuery(query.Format, args)
+}
+
+// Validate ensures that each of the given queries exists and that its placeholders
+// exactly match the given arg names. This is meant to be called at startup so that
+// mismatches between the SQL files and the calling code are reported early.
+func (r *QueryRegistry) Validate(expectedArgs map[string][]string) error {
+	names := make([]string, 0, len(expectedArgs))
+	for name := range expectedArgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		query, ok := r.queries[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown query %q", name))
+			continue
+		}
+
+		argNames := map[string]struct{}{}
+		for _, argName := range expectedArgs[name] {
+			argNames[argName] = struct{}{}
+		}
+
+		var missing, unused []string
+		for _, placeholder := range query.Placeholders {
+			if _, ok := argNames[placeholder]; !ok {
+				missing = append(missing, placeholder)
+			}
+			delete(argNames, placeholder)
+		}
+		for argName := range argNames {
+			unused = append(unused, argName)
+		}
+		sort.Strings(unused)
+
+		if len(missing) > 0 || len(unused) > 0 {
+			errs = append(errs, fmt.Errorf("query %q (%s): %w", name, query.Filename, &QueryArgsError{
+				Format:  query.Format,
+				Missing: missing,
+				Unused:  unused,
+			}))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,7 @@
+package pgutil
+
+import "embed"
+
+func NewEmbedQueryRegistry(fs embed.FS) (*QueryRegistry, error) {
+	return newQueryRegistry("<embed>", fs)
+}
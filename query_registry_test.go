@@ -0,0 +1,72 @@
+package pgutil
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryRegistry(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		registry, err := NewFilesystemQueryRegistry(path.Join("testdata", "queries", "valid"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"count-comments", "get-user", "search-users"}, registry.Names())
+
+		query, ok := registry.Get("search-users")
+		require.True(t, ok)
+		assert.Equal(t, path.Join("users", "users.sql"), query.Filename)
+		assert.Equal(t, []string{"prefix", "admin", "limit"}, query.Placeholders)
+
+		q := registry.Query("count-comments", Args{"user_id": 42})
+		sql, args := q.Format()
+		assert.Equal(t, "SELECT COUNT(*) FROM comments WHERE user_id = $1 OR author_id = $1;", sql)
+		assert.Equal(t, []any{42}, args)
+	})
+
+	t.Run("unknown query", func(t *testing.T) {
+		registry, err := NewFilesystemQueryRegistry(path.Join("testdata", "queries", "valid"))
+		require.NoError(t, err)
+
+		_, err = registry.TryQuery("delete-user", Args{"id": 42})
+		assert.EqualError(t, err, `unknown query "delete-user"`)
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		registry, err := NewFilesystemQueryRegistry(path.Join("testdata", "queries", "valid"))
+		require.NoError(t, err)
+
+		require.NoError(t, registry.Validate(map[string][]string{
+			"get-user":     {"id"},
+			"search-users": {"prefix", "admin", "limit"},
+		}))
+
+		err = registry.Validate(map[string][]string{
+			"get-user":     {"id"},
+			"search-users": {"prefix", "limt"},
+			"delete-user":  {"id"},
+		})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, `unknown query "delete-user"`)
+		assert.ErrorContains(t, err, `query "search-users" (users/users.sql): no arg supplied for "admin", "limit"; unused args "limt"`)
+
+		var argsErr *QueryArgsError
+		assert.ErrorAs(t, err, &argsErr)
+	})
+
+	t.Run("duplicate names", func(t *testing.T) {
+		_, err := NewFilesystemQueryRegistry(path.Join("testdata", "queries", "duplicate_names"))
+		assert.ErrorContains(t, err, `duplicate query name "get-user" (defined in a.sql and b.sql)`)
+	})
+
+	t.Run("unnamed query", func(t *testing.T) {
+		_, err := NewFilesystemQueryRegistry(path.Join("testdata", "queries", "unnamed"))
+		assert.ErrorContains(t, err, "a.sql: query text outside of a named query")
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := NewFilesystemQueryRegistry(path.Join("testdata", "queries", "missing"))
+		assert.ErrorContains(t, err, `query directory "testdata/queries/missing" does not exist`)
+	})
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestQuery(t *testing.T) {
@@ -140,3 +141,45 @@ func TestQuery(t *testing.T) {
 		})
 	})
 }
+
+func TestTryQuery(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		q, err := TryQuery("SELECT * FROM users WHERE id = {:id}", Args{"id": 42})
+		require.NoError(t, err)
+
+		query, args := q.Format()
+		assert.Equal(t, "SELECT * FROM users WHERE id = $1", query)
+		assert.Equal(t, []any{42}, args)
+	})
+
+	t.Run("missing args", func(t *testing.T) {
+		_, err := TryQuery("SELECT * FROM users WHERE id = {:id} AND name = {:name} OR id != {:id}", Args{
+			// empty
+		})
+
+		var argsErr *QueryArgsError
+		require.ErrorAs(t, err, &argsErr)
+		assert.Equal(t, []string{"id", "name"}, argsErr.Missing)
+		assert.Empty(t, argsErr.Unused)
+		assert.EqualError(t, err, `no arg supplied for "id", "name"`)
+	})
+
+	t.Run("unused args", func(t *testing.T) {
+		_, err := TryQuery("SELECT * FROM users WHERE id = {:id}", Args{"id": 42, "nmae": "efritz"})
+		require.NoError(t, err)
+
+		_, err = TryQueryStrict("SELECT * FROM users WHERE id = {:id}", Args{"id": 42, "nmae": "efritz"})
+
+		var argsErr *QueryArgsError
+		require.ErrorAs(t, err, &argsErr)
+		assert.Empty(t, argsErr.Missing)
+		assert.Equal(t, []string{"nmae"}, argsErr.Unused)
+		assert.EqualError(t, err, `unused args "nmae"`)
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		assert.PanicsWithValue(t, `no arg supplied for "id"`, func() {
+			Query("SELECT * FROM users WHERE id = {:id}", Args{})
+		})
+	})
+}
@@ -0,0 +1,403 @@
+package pgutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Queue is a durable job queue backed by the pgutil_queue_jobs table (see
+// NewQueueMigrationReader). Jobs are enqueued transactionally alongside other
+// writes and are claimed by workers with FOR UPDATE SKIP LOCKED. A claimed job is
+// invisible to other workers until its visibility timeout lapses; the timeout is
+// extended by heartbeats for as long as the job's handler is running.
+type Queue struct {
+	db                DB
+	name              string
+	maxAttempts       int
+	visibilityTimeout time.Duration
+	heartbeatInterval time.Duration
+	pollInterval      time.Duration
+	concurrency       int
+	backoff           func(attempt int) time.Duration
+	listenerURL       string
+}
+
+type Job struct {
+	ID          int64
+	Queue       string
+	Payload     json.RawMessage
+	Attempt     int
+	MaxAttempts int
+	LastError   *string
+	CreatedAt   time.Time
+}
+
+// Unmarshal decodes the job's payload into the given value.
+func (j Job) Unmarshal(v any) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// JobHandler processes a claimed job. Returning an error schedules the job to be
+// retried after a backoff, or dead-letters it once it has exhausted its attempts.
+// The given context is canceled if the job's claim is lost.
+type JobHandler func(ctx context.Context, job Job) error
+
+// ErrJobClaimLost is the cause of the cancellation of a handler's context when the
+// job's visibility timeout lapsed (or could not be extended before lapsing) and it may
+// have been claimed by another worker.
+var ErrJobClaimLost = errors.New("job claim lost")
+
+const queueNotifyChannel = "pgutil_queue"
+
+func NewQueue(db DB, name string, configs ...QueueConfigFunc) (*Queue, error) {
+	options := getQueueOptions(configs)
+	if options.maxAttempts < 1 {
+		return nil, fmt.Errorf("queue max attempts must be positive, got %d", options.maxAttempts)
+	}
+	if options.concurrency < 1 {
+		return nil, fmt.Errorf("queue concurrency must be positive, got %d", options.concurrency)
+	}
+	if options.pollInterval <= 0 {
+		return nil, fmt.Errorf("queue poll interval must be positive, got %s", options.pollInterval)
+	}
+
+	// Heartbeats are sent at a third of the visibility timeout
+	heartbeatInterval := options.visibilityTimeout / 3
+	if heartbeatInterval <= 0 {
+		return nil, fmt.Errorf("queue visibility timeout is too short, got %s", options.visibilityTimeout)
+	}
+
+	return &Queue{
+		db:                db,
+		name:              name,
+		maxAttempts:       options.maxAttempts,
+		visibilityTimeout: options.visibilityTimeout,
+		heartbeatInterval: heartbeatInterval,
+		pollInterval:      options.pollInterval,
+		concurrency:       options.concurrency,
+		backoff:           options.backoff,
+		listenerURL:       options.listenerURL,
+	}, nil
+}
+
+// Enqueue inserts a job with the given JSON-encodable payload. The given database
+// handle may be a transaction, in which case the job becomes visible to workers only
+// once the transaction commits.
+func (q *Queue) Enqueue(ctx context.Context, db DB, payload any, configs ...EnqueueConfigFunc) (int64, error) {
+	options := getEnqueueOptions(configs)
+
+	serialized, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	maxAttempts := q.maxAttempts
+	if options.maxAttempts > 0 {
+		maxAttempts = options.maxAttempts
+	}
+
+	id, _, err := scanJobID(db.Query(ctx, Query(`
+		INSERT INTO pgutil_queue_jobs (queue, payload, max_attempts, run_at)
+		VALUES ({:queue}, {:payload}, {:max_attempts}, current_timestamp + {:delay_ms} * interval '1 millisecond')
+		RETURNING id
+	`, Args{
+		"queue":        q.name,
+		"payload":      string(serialized),
+		"max_attempts": maxAttempts,
+		"delay_ms":     options.delay.Milliseconds(),
+	})))
+	if err != nil {
+		return 0, err
+	}
+
+	// Idle workers are woken when the enqueuing transaction commits, at which point
+	// the job becomes visible to them
+	if err := db.Exec(ctx, Query("SELECT pg_notify({:channel}, {:queue})", Args{
+		"channel": queueNotifyChannel,
+		"queue":   q.name,
+	})); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+var scanJobID = NewFirstScanner(NewAnyValueScanner[int64]())
+
+// Work claims and processes jobs until the given context is canceled. Jobs are
+// processed by up to the configured number of concurrent workers.
+func (q *Queue) Work(ctx context.Context, handler JobHandler) error {
+	var wakeup <-chan struct{}
+	if q.listenerURL != "" {
+		ch, closeListener, err := listenForWakeups(ctx, q.listenerURL, queueNotifyChannel, q.name)
+		if err != nil {
+			return err
+		}
+		defer closeListener()
+
+		wakeup = ch
+	}
+
+	// A failure in any worker (e.g., a database error) stops all of them
+	g, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < q.concurrency; i++ {
+		g.Go(func() error {
+			return q.work(ctx, handler, wakeup)
+		})
+	}
+
+	return g.Wait()
+}
+
+func (q *Queue) work(ctx context.Context, handler JobHandler, wakeup <-chan struct{}) error {
+	for {
+		processed, err := q.ProcessOne(ctx, handler)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if processed {
+			// Keep draining while there is work available
+			continue
+		}
+
+		select {
+		case <-time.After(q.pollInterval):
+		case <-wakeup:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// ProcessOne claims a single job and invokes the given handler with it. It returns
+// false if there were no jobs available.
+func (q *Queue) ProcessOne(ctx context.Context, handler JobHandler) (bool, error) {
+	job, ok, err := q.claim(ctx)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if job.Attempt > job.MaxAttempts {
+		// A previous worker claimed this job for its final attempt but never finished it
+		return true, q.finish(ctx, job, errors.New("visibility timeout exceeded on final attempt"))
+	}
+
+	handlerCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		done         = make(chan struct{})
+		heartbeatErr error
+	)
+	go func() {
+		defer close(done)
+		heartbeatErr = q.heartbeat(handlerCtx, job, cancel)
+	}()
+
+	handlerErr := handler(handlerCtx, job)
+	claimLost := errors.Is(context.Cause(handlerCtx), ErrJobClaimLost)
+	cancel(nil)
+	<-done
+
+	if claimLost {
+		// Another worker may own this job now; do not record the outcome
+		return true, heartbeatErr
+	}
+
+	if handlerErr != nil && ctx.Err() != nil {
+		return true, q.release(ctx, job)
+	}
+
+	return true, q.finish(ctx, job, handlerErr)
+}
+
+var (
+	scanJob  = NewFirstScanner(scanJobRow)
+	scanJobs = NewSliceScanner(scanJobRow)
+)
+
+func scanJobRow(s Scanner) (j Job, _ error) {
+	var payload []byte
+	err := s.Scan(&j.ID, &j.Queue, &payload, &j.Attempt, &j.MaxAttempts, &j.LastError, &j.CreatedAt)
+	j.Payload = payload
+	return j, err
+}
+
+func (q *Queue) claim(ctx context.Context) (Job, bool, error) {
+	return scanJob(q.db.Query(ctx, Query(`
+		WITH candidate AS (
+			SELECT id
+			FROM pgutil_queue_jobs
+			WHERE
+				queue = {:queue} AND (
+					(state = 'pending' AND run_at <= current_timestamp) OR
+					(state = 'running' AND locked_until < current_timestamp)
+				)
+			ORDER BY run_at, id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		UPDATE pgutil_queue_jobs j
+		SET
+			state = 'running',
+			attempts = j.attempts + 1,
+			locked_until = current_timestamp + {:timeout_ms} * interval '1 millisecond',
+			last_heartbeat_at = current_timestamp
+		FROM candidate
+		WHERE j.id = candidate.id
+		RETURNING j.id, j.queue, j.payload, j.attempts, j.max_attempts, j.last_error, j.created_at
+	`, Args{
+		"queue":      q.name,
+		"timeout_ms": q.visibilityTimeout.Milliseconds(),
+	})))
+}
+
+// heartbeat extends the visibility timeout of the given job until the given context
+// is canceled. Updates are fenced on the attempt number so that a worker whose claim
+// has lapsed and been taken over cannot extend the new owner's claim. Failed updates
+// are retried while the current claim is still valid; once it would lapse, the given
+// context is canceled with ErrJobClaimLost and the update's error is returned.
+func (q *Queue) heartbeat(ctx context.Context, job Job, cancel context.CancelCauseFunc) error {
+	ticker := time.NewTicker(q.heartbeatInterval)
+	defer ticker.Stop()
+
+	// The claim was extended when the job was claimed, just before the heartbeat began
+	extendedAt := time.Now()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+
+		_, ok, err := scanJobID(q.db.Query(ctx, Query(`
+			UPDATE pgutil_queue_jobs
+			SET
+				locked_until = current_timestamp + {:timeout_ms} * interval '1 millisecond',
+				last_heartbeat_at = current_timestamp
+			WHERE id = {:id} AND state = 'running' AND attempts = {:attempt}
+			RETURNING id
+		`, Args{
+			"id":         job.ID,
+			"attempt":    job.Attempt,
+			"timeout_ms": q.visibilityTimeout.Milliseconds(),
+		})))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if time.Since(extendedAt)+q.heartbeatInterval < q.visibilityTimeout {
+				continue
+			}
+
+			err = fmt.Errorf("failed to extend visibility timeout of job %d: %w", job.ID, err)
+			cancel(errors.Join(ErrJobClaimLost, err))
+			return err
+		}
+		if !ok {
+			cancel(ErrJobClaimLost)
+			return nil
+		}
+
+		extendedAt = time.Now()
+	}
+}
+
+// finish records the outcome of the given job's attempt. Failed jobs are retried
+// after a backoff until they exhaust their attempts, then dead-lettered.
+func (q *Queue) finish(ctx context.Context, job Job, handlerErr error) error {
+	ctx = context.WithoutCancel(ctx)
+
+	if handlerErr == nil {
+		return q.db.Exec(ctx, Query(`
+			UPDATE pgutil_queue_jobs
+			SET state = 'completed', locked_until = NULL, finished_at = current_timestamp
+			WHERE id = {:id} AND attempts = {:attempt}
+		`, Args{
+			"id":      job.ID,
+			"attempt": job.Attempt,
+		}))
+	}
+
+	if job.Attempt >= job.MaxAttempts {
+		return q.db.Exec(ctx, Query(`
+			UPDATE pgutil_queue_jobs
+			SET state = 'dead', locked_until = NULL, finished_at = current_timestamp, last_error = {:error}
+			WHERE id = {:id} AND attempts = {:attempt}
+		`, Args{
+			"id":      job.ID,
+			"attempt": job.Attempt,
+			"error":   handlerErr.Error(),
+		}))
+	}
+
+	return q.db.Exec(ctx, Query(`
+		UPDATE pgutil_queue_jobs
+		SET
+			state = 'pending',
+			locked_until = NULL,
+			run_at = current_timestamp + {:backoff_ms} * interval '1 millisecond',
+			last_error = {:error}
+		WHERE id = {:id} AND attempts = {:attempt}
+	`, Args{
+		"id":         job.ID,
+		"attempt":    job.Attempt,
+		"backoff_ms": q.backoff(job.Attempt).Milliseconds(),
+		"error":      handlerErr.Error(),
+	}))
+}
+
+// release returns a job interrupted by shutdown to the queue without consuming
+// one of its attempts.
+func (q *Queue) release(ctx context.Context, job Job) error {
+	return q.db.Exec(context.WithoutCancel(ctx), Query(`
+		UPDATE pgutil_queue_jobs
+		SET state = 'pending', locked_until = NULL, attempts = attempts - 1
+		WHERE id = {:id} AND attempts = {:attempt}
+	`, Args{
+		"id":      job.ID,
+		"attempt": job.Attempt,
+	}))
+}
+
+// DeadJobs returns the jobs of this queue that have exhausted their attempts.
+func (q *Queue) DeadJobs(ctx context.Context) ([]Job, error) {
+	return scanJobs(q.db.Query(ctx, Query(`
+		SELECT id, queue, payload, attempts, max_attempts, last_error, created_at
+		FROM pgutil_queue_jobs
+		WHERE queue = {:queue} AND state = 'dead'
+		ORDER BY id
+	`, Args{
+		"queue": q.name,
+	})))
+}
+
+// Retry returns a dead job to the queue with a fresh set of attempts.
+func (q *Queue) Retry(ctx context.Context, id int64) error {
+	_, ok, err := scanJobID(q.db.Query(ctx, Query(`
+		UPDATE pgutil_queue_jobs
+		SET state = 'pending', attempts = 0, run_at = current_timestamp, finished_at = NULL
+		WHERE id = {:id} AND queue = {:queue} AND state = 'dead'
+		RETURNING id
+	`, Args{
+		"id":    id,
+		"queue": q.name,
+	})))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("job %d is not dead-lettered (%w)", id, ErrDoesNotExist)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package pgutil
+
+import (
+	"embed"
+	"path"
+)
+
+//go:embed queue_migration/*.sql
+var queueMigrationFS embed.FS
+
+// NewQueueMigrationReader returns a reader of a single migration, with the given
+// identifier, that creates the table backing Queue. Combine it with the reader of
+// the application's own migrations via NewMultiMigrationReader.
+func NewQueueMigrationReader(id int) MigrationReader {
+	return newEmbeddedMigrationReader(queueMigrationFS, "queue_migration", id, "create pgutil queue jobs")
+}
+
+func newEmbeddedMigrationReader(fs embed.FS, dirname string, id int, name string) MigrationReader {
+	return MigrationReaderFunc(func() ([]RawDefinition, error) {
+		upQuery, err := readFile(fs, path.Join(dirname, "up.sql"))
+		if err != nil {
+			return nil, err
+		}
+
+		downQuery, err := readFile(fs, path.Join(dirname, "down.sql"))
+		if err != nil {
+			return nil, err
+		}
+
+		return []RawDefinition{{
+			ID:           id,
+			Name:         name,
+			RawUpQuery:   string(upQuery),
+			RawDownQuery: string(downQuery),
+		}}, nil
+	})
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pgutil_queue_jobs;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS pgutil_queue_jobs (
+    id bigserial PRIMARY KEY,
+    queue text NOT NULL,
+    payload jsonb NOT NULL,
+    state text NOT NULL DEFAULT 'pending' CHECK (state IN ('pending', 'running', 'completed', 'dead')),
+    attempts integer NOT NULL DEFAULT 0,
+    max_attempts integer NOT NULL,
+    run_at timestamptz NOT NULL DEFAULT current_timestamp,
+    locked_until timestamptz,
+    last_heartbeat_at timestamptz,
+    last_error text,
+    created_at timestamptz NOT NULL DEFAULT current_timestamp,
+    finished_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS pgutil_queue_jobs_pending ON pgutil_queue_jobs (queue, run_at, id) WHERE state = 'pending';
+CREATE INDEX IF NOT EXISTS pgutil_queue_jobs_running ON pgutil_queue_jobs (queue, locked_until) WHERE state = 'running';
@@ -0,0 +1,130 @@
+package pgutil
+
+import "time"
+
+type (
+	queueOptions struct {
+		maxAttempts       int
+		visibilityTimeout time.Duration
+		pollInterval      time.Duration
+		concurrency       int
+		backoff           func(attempt int) time.Duration
+		listenerURL       string
+	}
+
+	// QueueConfigFunc is a function used to configure a queue.
+	QueueConfigFunc func(*queueOptions)
+)
+
+func getQueueOptions(configs []QueueConfigFunc) *queueOptions {
+	options := &queueOptions{
+		maxAttempts:       5,
+		visibilityTimeout: 30 * time.Second,
+		pollInterval:      time.Second,
+		concurrency:       1,
+		backoff:           ExponentialBackoff(time.Second, time.Hour),
+	}
+
+	for _, f := range configs {
+		f(options)
+	}
+
+	return options
+}
+
+// WithQueueMaxAttempts sets the number of times a job is attempted before it is
+// dead-lettered. This can be overridden per job with WithEnqueueMaxAttempts.
+func WithQueueMaxAttempts(maxAttempts int) QueueConfigFunc {
+	return func(o *queueOptions) {
+		o.maxAttempts = maxAttempts
+	}
+}
+
+// WithQueueVisibilityTimeout sets how long a claimed job stays invisible to other
+// workers without a heartbeat. Heartbeats are sent at a third of this interval.
+func WithQueueVisibilityTimeout(timeout time.Duration) QueueConfigFunc {
+	return func(o *queueOptions) {
+		o.visibilityTimeout = timeout
+	}
+}
+
+// WithQueuePollInterval sets how long an idle worker waits before checking for new jobs.
+func WithQueuePollInterval(interval time.Duration) QueueConfigFunc {
+	return func(o *queueOptions) {
+		o.pollInterval = interval
+	}
+}
+
+// WithQueueConcurrency sets the number of jobs processed concurrently by Work.
+func WithQueueConcurrency(concurrency int) QueueConfigFunc {
+	return func(o *queueOptions) {
+		o.concurrency = concurrency
+	}
+}
+
+// WithQueueBackoff sets the delay before a failed job is retried, given the number
+// of the attempt that failed.
+func WithQueueBackoff(backoff func(attempt int) time.Duration) QueueConfigFunc {
+	return func(o *queueOptions) {
+		o.backoff = backoff
+	}
+}
+
+// WithQueueListener wakes idle workers as soon as jobs are enqueued via LISTEN/NOTIFY
+// on a dedicated connection to the given database. Workers still poll at the poll
+// interval, which is how delayed jobs and jobs with lapsed visibility timeouts are
+// found, as neither produces a notification.
+func WithQueueListener(databaseURL string) QueueConfigFunc {
+	return func(o *queueOptions) {
+		o.listenerURL = databaseURL
+	}
+}
+
+// ExponentialBackoff returns a backoff function that doubles the delay with each
+// attempt, starting at base and capped at max.
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+
+		if delay > max {
+			return max
+		}
+
+		return delay
+	}
+}
+
+type (
+	enqueueOptions struct {
+		delay       time.Duration
+		maxAttempts int
+	}
+
+	// EnqueueConfigFunc is a function used to configure an enqueued job.
+	EnqueueConfigFunc func(*enqueueOptions)
+)
+
+func getEnqueueOptions(configs []EnqueueConfigFunc) *enqueueOptions {
+	options := &enqueueOptions{}
+	for _, f := range configs {
+		f(options)
+	}
+
+	return options
+}
+
+// WithEnqueueDelay makes the job available to workers only after the given delay.
+func WithEnqueueDelay(delay time.Duration) EnqueueConfigFunc {
+	return func(o *enqueueOptions) {
+		o.delay = delay
+	}
+}
+
+func WithEnqueueMaxAttempts(maxAttempts int) EnqueueConfigFunc {
+	return func(o *enqueueOptions) {
+		o.maxAttempts = maxAttempts
+	}
+}
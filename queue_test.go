@@ -0,0 +1,250 @@
+package pgutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-nacelle/log/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, configs ...QueueConfigFunc) (DB, *Queue) {
+		db := NewTestDB(t)
+		runner, err := NewMigrationRunner(db, NewQueueMigrationReader(1), log.NewNilLogger())
+		require.NoError(t, err)
+		require.NoError(t, runner.ApplyAll(ctx))
+
+		configs = append([]QueueConfigFunc{WithQueueBackoff(func(int) time.Duration { return 0 })}, configs...)
+		queue, err := NewQueue(db, "test", configs...)
+		require.NoError(t, err)
+		return db, queue
+	}
+
+	type payload struct {
+		Message string `json:"message"`
+	}
+
+	t.Run("transactional enqueue", func(t *testing.T) {
+		db, queue := setup(t)
+
+		errRollback := errors.New("rollback")
+		err := db.WithTransaction(ctx, func(tx DB) error {
+			_, err := queue.Enqueue(ctx, tx, payload{Message: "discarded"})
+			require.NoError(t, err)
+			return errRollback
+		})
+		require.ErrorIs(t, err, errRollback)
+
+		require.NoError(t, db.WithTransaction(ctx, func(tx DB) error {
+			_, err := queue.Enqueue(ctx, tx, payload{Message: "hello"})
+			return err
+		}))
+
+		var messages []string
+		handler := func(ctx context.Context, job Job) error {
+			var p payload
+			require.NoError(t, job.Unmarshal(&p))
+			messages = append(messages, p.Message)
+			return nil
+		}
+
+		processed, err := queue.ProcessOne(ctx, handler)
+		require.NoError(t, err)
+		assert.True(t, processed)
+
+		processed, err = queue.ProcessOne(ctx, handler)
+		require.NoError(t, err)
+		assert.False(t, processed)
+
+		assert.Equal(t, []string{"hello"}, messages)
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		db, queue := setup(t)
+
+		_, err := queue.Enqueue(ctx, db, payload{}, WithEnqueueDelay(time.Hour))
+		require.NoError(t, err)
+
+		processed, err := queue.ProcessOne(ctx, func(ctx context.Context, job Job) error { return nil })
+		require.NoError(t, err)
+		assert.False(t, processed)
+	})
+
+	t.Run("retries and dead letters", func(t *testing.T) {
+		db, queue := setup(t, WithQueueMaxAttempts(3))
+
+		id, err := queue.Enqueue(ctx, db, payload{})
+		require.NoError(t, err)
+
+		var attempts []int
+		handler := func(ctx context.Context, job Job) error {
+			attempts = append(attempts, job.Attempt)
+			return errors.New("oops")
+		}
+
+		for {
+			processed, err := queue.ProcessOne(ctx, handler)
+			require.NoError(t, err)
+			if !processed {
+				break
+			}
+		}
+		assert.Equal(t, []int{1, 2, 3}, attempts)
+
+		deadJobs, err := queue.DeadJobs(ctx)
+		require.NoError(t, err)
+		require.Len(t, deadJobs, 1)
+		assert.Equal(t, id, deadJobs[0].ID)
+		require.NotNil(t, deadJobs[0].LastError)
+		assert.Equal(t, "oops", *deadJobs[0].LastError)
+
+		// Retry dead-lettered job
+		require.NoError(t, queue.Retry(ctx, id))
+		processed, err := queue.ProcessOne(ctx, func(ctx context.Context, job Job) error {
+			assert.Equal(t, 1, job.Attempt)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.True(t, processed)
+
+		assert.ErrorIs(t, queue.Retry(ctx, id), ErrDoesNotExist)
+	})
+
+	t.Run("visibility timeout", func(t *testing.T) {
+		db, queue := setup(t, WithQueueVisibilityTimeout(300*time.Millisecond))
+
+		_, err := queue.Enqueue(ctx, db, payload{})
+		require.NoError(t, err)
+
+		// Simulate a worker that claimed the job and then disappeared
+		_, ok, err := queue.claim(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		processed, err := queue.ProcessOne(ctx, func(ctx context.Context, job Job) error { return nil })
+		require.NoError(t, err)
+		assert.False(t, processed)
+
+		time.Sleep(400 * time.Millisecond)
+
+		// Heartbeats keep a long-running job claimed past its visibility timeout
+		processed, err = queue.ProcessOne(ctx, func(ctx context.Context, job Job) error {
+			assert.Equal(t, 2, job.Attempt)
+
+			time.Sleep(600 * time.Millisecond)
+
+			processed, err := queue.ProcessOne(ctx, func(ctx context.Context, job Job) error { return nil })
+			require.NoError(t, err)
+			assert.False(t, processed)
+			return ctx.Err()
+		})
+		require.NoError(t, err)
+		assert.True(t, processed)
+	})
+
+	t.Run("heartbeat failure", func(t *testing.T) {
+		db, _ := setup(t)
+		queue, err := NewQueue(heartbeatFailingDB{db}, "test", WithQueueVisibilityTimeout(300*time.Millisecond))
+		require.NoError(t, err)
+
+		_, err = queue.Enqueue(ctx, db, payload{})
+		require.NoError(t, err)
+
+		var cause error
+		processed, err := queue.ProcessOne(ctx, func(ctx context.Context, job Job) error {
+			<-ctx.Done()
+			cause = context.Cause(ctx)
+			return ctx.Err()
+		})
+		assert.True(t, processed)
+		require.ErrorContains(t, err, "failed to extend visibility timeout")
+		require.ErrorIs(t, cause, ErrJobClaimLost)
+	})
+
+	t.Run("work", func(t *testing.T) {
+		db, queue := setup(t, WithQueueConcurrency(4), WithQueuePollInterval(10*time.Millisecond))
+
+		for i := 0; i < 20; i++ {
+			_, err := queue.Enqueue(ctx, db, payload{})
+			require.NoError(t, err)
+		}
+
+		var (
+			mu   sync.Mutex
+			seen = map[int64]struct{}{}
+		)
+
+		workCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- queue.Work(workCtx, func(ctx context.Context, job Job) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				_, ok := seen[job.ID]
+				assert.False(t, ok)
+				seen[job.ID] = struct{}{}
+				return nil
+			})
+		}()
+
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(seen) == 20
+		}, 5*time.Second, 10*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-errs)
+	})
+}
+
+func TestNewQueueInvalidOptions(t *testing.T) {
+	_, err := NewQueue(nil, "test", WithQueueVisibilityTimeout(0))
+	require.ErrorContains(t, err, "queue visibility timeout is too short, got 0s")
+
+	_, err = NewQueue(nil, "test", WithQueueVisibilityTimeout(2))
+	require.ErrorContains(t, err, "queue visibility timeout is too short, got 2ns")
+
+	_, err = NewQueue(nil, "test", WithQueueConcurrency(0))
+	require.ErrorContains(t, err, "queue concurrency must be positive, got 0")
+
+	_, err = NewQueue(nil, "test", WithQueueMaxAttempts(0))
+	require.ErrorContains(t, err, "queue max attempts must be positive, got 0")
+
+	_, err = NewQueue(nil, "test", WithQueuePollInterval(-time.Second))
+	require.ErrorContains(t, err, "queue poll interval must be positive, got -1s")
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 10*time.Second)
+
+	assert.Equal(t, time.Second, backoff(1))
+	assert.Equal(t, 2*time.Second, backoff(2))
+	assert.Equal(t, 8*time.Second, backoff(4))
+	assert.Equal(t, 10*time.Second, backoff(5))
+	assert.Equal(t, 10*time.Second, backoff(100))
+}
+
+// heartbeatFailingDB fails every attempt to extend a job's visibility timeout.
+type heartbeatFailingDB struct {
+	DB
+}
+
+func (db heartbeatFailingDB) Query(ctx context.Context, q Q) (Rows, error) {
+	if query, _ := q.Format(); strings.Contains(query, "last_heartbeat_at = current_timestamp") && strings.Contains(query, "RETURNING id") {
+		return nil, errors.New("connection reset")
+	}
+
+	return db.DB.Query(ctx, q)
+}
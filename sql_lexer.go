@@ -0,0 +1,144 @@
+package pgutil
+
+import (
+	"regexp"
+	"strings"
+)
+
+type sqlSegmentKind int
+
+const (
+	sqlSegmentCode    sqlSegmentKind = iota
+	sqlSegmentQuoted                 // string literals, quoted identifiers, and dollar-quoted bodies
+	sqlSegmentComment                // line and block comments
+)
+
+type sqlSegment struct {
+	kind sqlSegmentKind
+	text string
+}
+
+var dollarQuoteTagPattern = regexp.MustCompile(`^\$(?:[A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// splitSQL splits the given query into runs of code, quoted text, and comments so
+// that callers can rewrite code without touching the contents of literals. This is
+// a lexer, not a parser: unterminated quotes and comments extend to the end of the
+// query, and the concatenation of all segments is always the original query.
+func splitSQL(query string) []sqlSegment {
+	var (
+		segments []sqlSegment
+		start    = 0
+	)
+
+	emit := func(kind sqlSegmentKind, from, to int) {
+		if start < from {
+			segments = append(segments, sqlSegment{kind: sqlSegmentCode, text: query[start:from]})
+		}
+		segments = append(segments, sqlSegment{kind: kind, text: query[from:to]})
+		start = to
+	}
+
+	for i := 0; i < len(query); {
+		switch {
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+
+			emit(sqlSegmentComment, i, i+end)
+			i += end
+
+		case strings.HasPrefix(query[i:], "/*"):
+			end := blockCommentEnd(query, i)
+			emit(sqlSegmentComment, i, end)
+			i = end
+
+		case query[i] == '\'':
+			// E'...' strings allow backslash escapes
+			escapes := i > 0 && (query[i-1] == 'E' || query[i-1] == 'e') && (i < 2 || !isSQLIdentifierByte(query[i-2]))
+			end := quotedEnd(query, i, '\'', escapes)
+			emit(sqlSegmentQuoted, i, end)
+			i = end
+
+		case query[i] == '"':
+			end := quotedEnd(query, i, '"', false)
+			emit(sqlSegmentQuoted, i, end)
+			i = end
+
+		case query[i] == '$' && (i == 0 || !isSQLIdentifierByte(query[i-1])):
+			tag := dollarQuoteTagPattern.FindString(query[i:])
+			if tag == "" {
+				// Positional parameter (e.g., `$1`)
+				i++
+				continue
+			}
+
+			end := len(query)
+			if index := strings.Index(query[i+len(tag):], tag); index >= 0 {
+				end = i + len(tag) + index + len(tag)
+			}
+
+			emit(sqlSegmentQuoted, i, end)
+			i = end
+
+		default:
+			i++
+		}
+	}
+
+	if start < len(query) {
+		segments = append(segments, sqlSegment{kind: sqlSegmentCode, text: query[start:]})
+	}
+
+	return segments
+}
+
+// quotedEnd returns the index following the quote that closes the quoted text that
+// begins at the given index. Doubled quotes are part of the quoted text.
+func quotedEnd(query string, i int, quote byte, escapes bool) int {
+	for j := i + 1; j < len(query); j++ {
+		switch {
+		case escapes && query[j] == '\\':
+			j++
+		case query[j] == quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+
+			return j + 1
+		}
+	}
+
+	return len(query)
+}
+
+// blockCommentEnd returns the index following the block comment that begins at the
+// given index. Postgres block comments nest.
+func blockCommentEnd(query string, i int) int {
+	depth := 0
+	for j := i; j < len(query)-1; j++ {
+		switch {
+		case query[j] == '/' && query[j+1] == '*':
+			depth++
+			j++
+		case query[j] == '*' && query[j+1] == '/':
+			depth--
+			j++
+
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+
+	return len(query)
+}
+
+func isSQLIdentifierByte(b byte) bool {
+	return b == '_' || b == '$' || b >= 0x80 ||
+		('a' <= b && b <= 'z') ||
+		('A' <= b && b <= 'Z') ||
+		('0' <= b && b <= '9')
+}
@@ -0,0 +1 @@
+-- 1_first
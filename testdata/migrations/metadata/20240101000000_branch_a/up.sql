@@ -0,0 +1 @@
+-- 20240101000000_branch_a
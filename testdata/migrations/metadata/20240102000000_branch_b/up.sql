@@ -0,0 +1 @@
+-- 20240102000000_branch_b
@@ -0,0 +1 @@
+-- 20240103000000_merge
@@ -0,0 +1 @@
+CREATE TABLE IF NOT EXISTS "public"."users"();
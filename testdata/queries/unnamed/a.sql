@@ -0,0 +1,4 @@
+SELECT * FROM users;
+
+-- name: get-user
+SELECT * FROM users WHERE id = {:id};
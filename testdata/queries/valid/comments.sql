@@ -0,0 +1,4 @@
+-- Queries over the comments table
+
+-- name: count-comments
+SELECT COUNT(*) FROM comments WHERE user_id = {:user_id} OR author_id = {:user_id};
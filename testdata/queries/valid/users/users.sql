@@ -0,0 +1,12 @@
+-- name: get-user
+SELECT id, name, email
+FROM users
+WHERE id = {:id};
+
+-- name: search-users
+-- Users matching a name prefix
+SELECT id, name, email
+FROM users
+WHERE name LIKE {:prefix} || '%' AND ({:admin} OR NOT admin)
+ORDER BY name
+LIMIT {:limit};
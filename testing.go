@@ -17,7 +17,7 @@ func NewTestDB(t testing.TB) DB {
 	return NewTestDBWithLogger(t, log.NewNilLogger())
 }
 
-func NewTestDBWithLogger(t testing.TB, logger log.Logger) DB {
+func NewTestDBWithLogger(t testing.TB, logger log.Logger, configs ...DialConfigFunc) DB {
 	t.Helper()
 
 	id, err := randomHexString(16)
@@ -65,5 +65,5 @@ func NewTestDBWithLogger(t testing.TB, logger log.Logger) DB {
 		require.NoError(t, rawLoggingDB.Exec(context.Background(), dropDatabaseQuery))
 	})
 
-	return newLoggingDB(testDB, logger)
+	return newLoggingDB(testDB, logger, configs...)
 }